@@ -8,6 +8,7 @@ import (
 	"infra-foundation/packet"
 	"infra-foundation/protomessage"
 	"infra-foundation/session"
+	"slices"
 	"sync/atomic"
 
 	"google.golang.org/protobuf/proto"
@@ -26,15 +27,52 @@ func newAcceptor(s *session.NetworkEntities, svr Server) *acceptor {
 }
 
 func (a *acceptor) Send(pb protomessage.ProtoMessage) error {
-	pdata, err := proto.Marshal(pb)
+	pdata, err := marshalBuffer(pb)
 	if err != nil {
 		return fmt.Errorf("[acceptor/Send] proto Marshal %w", err)
 	}
-	bdata, err := a.codec.Pack(packet.Data, pb.MessageID(), a.ID(), pdata)
+	defer pdata.Free()
+	return a.sendClient(packet.Header{Type: packet.Data, ID: pb.MessageID(), SID: a.ID()}, pdata.B, pb.NodeName())
+}
+
+func (a *acceptor) Reply(seq uint32, pb protomessage.ProtoMessage) error {
+	pdata, err := marshalBuffer(pb)
 	if err != nil {
-		return fmt.Errorf("[acceptor/Send] codec Pack %w", err)
+		return fmt.Errorf("[acceptor/Reply] proto Marshal %w", err)
 	}
-	return remoteCall(a, a.codec, packet.NewInternal(packet.ClientData, 0, a.ID(), bdata), pb.NodeName())
+	defer pdata.Free()
+	return a.sendClient(packet.Header{Type: packet.Data, ID: pb.MessageID(), SID: a.ID(), Seq: seq}, pdata.B, pb.NodeName())
+}
+
+func (a *acceptor) ReplyError(seq uint32, id int32, code int32, msg string) error {
+	pdata, err := marshalBuffer(&M2CError{Code: code, Message: msg})
+	if err != nil {
+		return fmt.Errorf("[acceptor/ReplyError] proto Marshal %w", err)
+	}
+	defer pdata.Free()
+	return a.sendClient(packet.Header{Type: packet.Error, ID: id, SID: a.ID(), Seq: seq}, pdata.B, "")
+}
+
+// replyCodec packs the answers to sequenced requests. Only V2 clients send a
+// sequence, so they can take the V2 header carrying it back.
+var replyCodec = packet.NewPackCodecWithVersion(packet.V2)
+
+// sendClient packs payload under h for the client and nests it in the
+// ClientData packet carrying it to its gate.
+func (a *acceptor) sendClient(h packet.Header, payload []byte, nodeName string) error {
+	agent, err := remoteAgent(a, 0, nodeName)
+	if err != nil {
+		return err
+	}
+	codec := a.codec
+	if h.Seq != 0 {
+		codec = replyCodec
+	}
+	b, err := codec.PackNested(packet.Header{Type: packet.ClientData, SID: a.ID()}, h, payload)
+	if err != nil {
+		return fmt.Errorf("[acceptor/sendClient] codec Pack %w", err)
+	}
+	return sendBuffer(agent, b)
 }
 
 func (a *acceptor) Notify(s []session.Session, pb protomessage.ProtoMessage) error {
@@ -46,26 +84,17 @@ func (a *acceptor) Notify(s []session.Session, pb protomessage.ProtoMessage) err
 	if err != nil {
 		return fmt.Errorf("[acceptor/Notify] codec Pack %w", err)
 	}
-	var tempSession = map[int64][]int64{}
+	sessions := slices.Values(s)
 	if len(s) == 0 {
-		if err = a.connManager.Range(func(s session.Session) error {
-			agent, err := defaultNodeAgent.getGateNode(s)
-			if err != nil {
-				return err
-			}
-			tempSession[agent.ID()] = append(tempSession[agent.ID()], s.ID())
-			return nil
-		}); err != nil {
-			return fmt.Errorf("[acceptor/Notify] Range %w", err)
-		}
-	} else {
-		for _, sv := range s {
-			agent, err := defaultNodeAgent.getGateNode(sv)
-			if err != nil {
-				return err
-			}
-			tempSession[agent.ID()] = append(tempSession[agent.ID()], sv.ID())
+		sessions = a.connManager.All()
+	}
+	var tempSession = map[int64][]int64{}
+	for sv := range sessions {
+		agent, err := defaultNodeAgent.getGateNode(sv)
+		if err != nil {
+			return err
 		}
+		tempSession[agent.ID()] = append(tempSession[agent.ID()], sv.ID())
 	}
 
 	var pb2 = &N2MNotify{}
@@ -73,19 +102,11 @@ func (a *acceptor) Notify(s []session.Session, pb protomessage.ProtoMessage) err
 	for sid, usids := range tempSession {
 		pb2.SessionID = usids
 		pb2.Plyload = bdata
-		bdata2, err := proto.Marshal(pb2)
-		if err != nil {
-			return err
-		}
 		asion, ok := defaultNodeAgent.connManager.GetByID(sid)
 		if !ok {
 			return fmt.Errorf("[acceptor/Notify] %d not found", sid)
 		}
-		bdata, err := a.codec.Pack(packet.NotifyData, 0, 0, bdata2)
-		if err != nil {
-			return err
-		}
-		errs = append(errs, asion.(sender).SendData(bdata))
+		errs = append(errs, asion.(sender).SendTypePb(packet.NotifyData, pb2))
 	}
 	if err = errors.Join(errs...); err != nil {
 		return fmt.Errorf("[acceptor/Notify] remoteCall %w", err)
@@ -98,6 +119,47 @@ func (a *acceptor) Close() error {
 		return nil
 	}
 	a.modelManager.OnDisconnection(a)
+	a.modelManager.OnSessionClose(a)
 	a.connManager.RemoveByID(a.ID())
 	return nil
 }
+
+func (a *acceptor) BindUID(uid int64) {
+	old := a.UID()
+	a.NetworkEntities.BindUID(uid)
+	a.connManager.RebindUID(a, old)
+	if uid > 0 && uid != old {
+		a.modelManager.OnBindUID(a, uid)
+	}
+}
+
+// bindAcceptor applies an N2MOnSessionBindServer to the local session,
+// creating its acceptor on first sight, and delivers the session events. A
+// packet carrying only attributes updates an existing session and is
+// otherwise dropped.
+func bindAcceptor(connManager *connmannger.ConnManager, pb *N2MOnSessionBindServer) session.Session {
+	conn, ok := connManager.GetByID(pb.SessionID)
+	if !ok {
+		if len(pb.GetServers()) == 0 {
+			return nil
+		}
+		a := newAcceptor(session.NewNetworkEntities(pb.SessionID, -1), defaultNodeAgent.svr)
+		connManager.StoreSession(a)
+		a.modelManager.OnConnection(a)
+		conn = a
+	}
+	conn.Attrs().Apply(pb.GetAttrs())
+	if pb.UID > 0 {
+		conn.BindUID(pb.UID)
+	}
+	if len(pb.GetServers()) == 0 {
+		return conn
+	}
+	for name, id := range pb.GetServers() {
+		conn.BindServers(name, id)
+	}
+	if a, ok := conn.(*acceptor); ok {
+		a.modelManager.OnBindServer(a, a.Servers())
+	}
+	return conn
+}
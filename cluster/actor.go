@@ -0,0 +1,368 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infra-foundation/logx"
+	"infra-foundation/model"
+	"infra-foundation/packet"
+	"infra-foundation/protomessage"
+	"infra-foundation/scheduler"
+	"infra-foundation/session"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Actor is a virtual actor: it exists logically for every key of its kind and
+// is activated on the node that owns the key the first time a message for it
+// arrives. Receive is never called concurrently for the same key.
+type Actor interface {
+	OnActivate(ref ActorRef) error
+	OnDeactivate()
+	Receive(ctx context.Context, msg protomessage.ProtoMessage) (protomessage.ProtoMessage, error)
+}
+
+type ActorFactory func(key string) Actor
+
+// ActorOptions configures an actor kind. Clock drives idle passivation and
+// defaults to scheduler.SystemClock.
+type ActorOptions struct {
+	IdleTimeout time.Duration
+	Shards      int
+	Clock       scheduler.Clock
+}
+
+type ActorRef struct {
+	Kind string
+	Key  string
+}
+
+var ErrActorKindNotFound = errors.New("cluster: actor kind not found")
+
+const (
+	defaultActorIdleTimeout = 10 * time.Minute
+	defaultActorShards      = 8
+)
+
+func ActorOf(kind, key string) ActorRef { return ActorRef{Kind: kind, Key: key} }
+
+func (r ActorRef) String() string { return r.Kind + "/" + r.Key }
+
+func (r ActorRef) Ask(ctx context.Context, msg protomessage.ProtoMessage) (protomessage.ProtoMessage, error) {
+	return defaultActors.ask(ctx, r, msg)
+}
+
+func (r ActorRef) Tell(msg protomessage.ProtoMessage) error {
+	return defaultActors.tell(r, msg)
+}
+
+func AskActor[Resp protomessage.ProtoMessage](ctx context.Context, ref ActorRef, msg protomessage.ProtoMessage) (Resp, error) {
+	var zero Resp
+	resp, err := ref.Ask(ctx, msg)
+	if err != nil {
+		return zero, err
+	}
+	if resp == nil {
+		return zero, nil
+	}
+	r, ok := resp.(Resp)
+	if !ok {
+		return zero, fmt.Errorf("[AskActor] %s replied %T", ref, resp)
+	}
+	return r, nil
+}
+
+// RegisterActor declares that this node can host actors of kind. It must be
+// called before the node registers itself with service discovery so that the
+// kind is advertised to the cluster.
+func RegisterActor(kind string, factory ActorFactory, opts ActorOptions) error {
+	return defaultActors.register(model.DefaultModelManager, kind, factory, opts)
+}
+
+type actorPoster interface {
+	PostKey(key uint64, f func()) error
+	PostFuncKey(key uint64, f func())
+	PushInfiniteTimer(interval time.Duration, infinite bool, f func()) scheduler.TimerID
+	Clock() scheduler.Clock
+}
+
+type activation struct {
+	actor    Actor
+	lastSeen time.Time
+}
+
+type actorKind struct {
+	kind    string
+	factory ActorFactory
+	opts    ActorOptions
+	poster  actorPoster
+	mu      sync.Mutex
+	actives map[string]*activation
+}
+
+func (k *actorKind) Name() string                    { return "actor/" + k.kind }
+func (k *actorKind) OnInit() error                   { return nil }
+func (k *actorKind) OnStart() error                  { return nil }
+func (k *actorKind) Shards() int                     { return k.opts.Shards }
+func (k *actorKind) OnDisconnection(session.Session) {}
+
+func (k *actorKind) MailboxConfig() scheduler.MailboxConfig {
+	return scheduler.MailboxConfig{Clock: k.opts.Clock}
+}
+
+func (k *actorKind) OnStop() error {
+	k.mu.Lock()
+	actives := k.actives
+	k.actives = map[string]*activation{}
+	k.mu.Unlock()
+	for _, a := range actives {
+		a.actor.OnDeactivate()
+	}
+	return nil
+}
+
+// receive runs on the mailbox shard that owns key.
+func (k *actorKind) receive(ctx context.Context, key string, msg protomessage.ProtoMessage) (protomessage.ProtoMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	k.mu.Lock()
+	a, ok := k.actives[key]
+	k.mu.Unlock()
+	if !ok {
+		a = &activation{actor: k.factory(key)}
+		if err := a.actor.OnActivate(ActorRef{Kind: k.kind, Key: key}); err != nil {
+			return nil, fmt.Errorf("[actor/%s] activate %s %w", k.kind, key, err)
+		}
+	}
+	k.mu.Lock()
+	a.lastSeen = k.poster.Clock().Now()
+	k.actives[key] = a
+	k.mu.Unlock()
+	return a.actor.Receive(ctx, msg)
+}
+
+func (k *actorKind) deactivate(key string, cond func(a *activation) bool) {
+	k.poster.PostFuncKey(hashString(key), func() {
+		k.mu.Lock()
+		a, ok := k.actives[key]
+		if !ok || !cond(a) {
+			k.mu.Unlock()
+			return
+		}
+		delete(k.actives, key)
+		k.mu.Unlock()
+		a.actor.OnDeactivate()
+	})
+}
+
+func (k *actorKind) collectIdle() {
+	now := k.poster.Clock().Now()
+	k.mu.Lock()
+	var idle []string
+	for key, a := range k.actives {
+		if now.Sub(a.lastSeen) >= k.opts.IdleTimeout {
+			idle = append(idle, key)
+		}
+	}
+	k.mu.Unlock()
+	for _, key := range idle {
+		k.deactivate(key, func(a *activation) bool { return k.poster.Clock().Now().Sub(a.lastSeen) >= k.opts.IdleTimeout })
+	}
+}
+
+func (k *actorKind) keys() []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keys := make([]string, 0, len(k.actives))
+	for key := range k.actives {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+type actorSystem struct {
+	mu    sync.RWMutex
+	kinds map[string]*actorKind
+	rings map[string]*hashRing
+}
+
+var defaultActors = &actorSystem{kinds: map[string]*actorKind{}, rings: map[string]*hashRing{}}
+
+func (s *actorSystem) register(mm *model.ModelManager, kind string, factory ActorFactory, opts ActorOptions) error {
+	if kind == "" || factory == nil {
+		return errors.New("[RegisterActor] kind is empty or factory is nil")
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = defaultActorIdleTimeout
+	}
+	if opts.Shards <= 0 {
+		opts.Shards = defaultActorShards
+	}
+	k := &actorKind{kind: kind, factory: factory, opts: opts, actives: map[string]*activation{}}
+	s.mu.Lock()
+	if _, ok := s.kinds[kind]; ok {
+		s.mu.Unlock()
+		return fmt.Errorf("[RegisterActor] duplicated actor kind %q", kind)
+	}
+	s.kinds[kind] = k
+	s.mu.Unlock()
+
+	if err := mm.Register(k); err != nil {
+		s.mu.Lock()
+		delete(s.kinds, kind)
+		s.mu.Unlock()
+		return fmt.Errorf("[RegisterActor] %w", err)
+	}
+	md, _ := mm.GetModel(k.Name())
+	k.poster = md
+	md.PushInfiniteTimer(max(opts.IdleTimeout/2, time.Second), true, k.collectIdle)
+	return nil
+}
+
+func (s *actorSystem) kind(kind string) (*actorKind, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.kinds[kind]
+	return k, ok
+}
+
+func (s *actorSystem) localKinds() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	kinds := make([]string, 0, len(s.kinds))
+	for kind := range s.kinds {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+// rebuild recomputes the placement rings from the live node set and
+// deactivates local actors whose key moved to another node.
+func (s *actorSystem) rebuild(nodes map[string][]*node) {
+	hosts := map[string][]string{}
+	for _, ns := range nodes {
+		for _, n := range ns {
+			for _, kind := range n.Actors {
+				hosts[kind] = append(hosts[kind], n.Id)
+			}
+		}
+	}
+	rings := make(map[string]*hashRing, len(hosts))
+	for kind, ids := range hosts {
+		slices.Sort(ids)
+		rings[kind] = newHashRing(slices.Compact(ids))
+	}
+	s.mu.Lock()
+	s.rings = rings
+	kinds := make([]*actorKind, 0, len(s.kinds))
+	for _, k := range s.kinds {
+		kinds = append(kinds, k)
+	}
+	s.mu.Unlock()
+
+	for _, k := range kinds {
+		for _, key := range k.keys() {
+			if _, local := s.locate(k.kind, key); !local {
+				k.deactivate(key, func(*activation) bool {
+					_, local := s.locate(k.kind, key)
+					return !local
+				})
+			}
+		}
+	}
+}
+
+func (s *actorSystem) locate(kind, key string) (string, bool) {
+	s.mu.RLock()
+	ring := s.rings[kind]
+	_, hosted := s.kinds[kind]
+	s.mu.RUnlock()
+	owner, ok := ring.owner(key)
+	self := defaultNodeAgent.node
+	if !ok || self == nil {
+		return "", hosted
+	}
+	return owner, owner == self.Id
+}
+
+func (s *actorSystem) deliver(ctx context.Context, ref ActorRef, msg protomessage.ProtoMessage, reply func(protomessage.ProtoMessage, error)) error {
+	k, ok := s.kind(ref.Kind)
+	if !ok {
+		return fmt.Errorf("[actor/deliver] %s %w", ref, ErrActorKindNotFound)
+	}
+	return k.poster.PostKey(hashString(ref.Key), func() {
+		resp, err := k.receive(ctx, ref.Key, msg)
+		if reply != nil {
+			reply(resp, err)
+		} else if err != nil {
+			logx.Err.Printf("[actor/deliver] %s %v", ref, err)
+		}
+	})
+}
+
+func (s *actorSystem) remote(kind, key string) (sender, error) {
+	owner, local := s.locate(kind, key)
+	if local {
+		return nil, nil
+	}
+	if owner == "" {
+		return nil, fmt.Errorf("[actor/remote] %s %w", kind, ErrActorKindNotFound)
+	}
+	id, err := strconv.ParseInt(owner, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("[actor/remote] node %q %w", owner, err)
+	}
+	conn, ok := defaultNodeAgent.connManager.GetByID(id)
+	if !ok {
+		return nil, fmt.Errorf("[actor/remote] node %s %w", owner, ErrRemoteNotFound)
+	}
+	return conn.(sender), nil
+}
+
+func (s *actorSystem) ask(ctx context.Context, ref ActorRef, msg protomessage.ProtoMessage) (protomessage.ProtoMessage, error) {
+	conn, err := s.remote(ref.Kind, ref.Key)
+	if err != nil {
+		return nil, err
+	}
+	if conn != nil {
+		typ, payload, err := marshalMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		return defaultRPC.call(ctx, conn, &N2MRequest{Kind: ref.Kind, Key: ref.Key, Type: typ, Payload: payload})
+	}
+	type result struct {
+		resp protomessage.ProtoMessage
+		err  error
+	}
+	ch := make(chan result, 1)
+	if err = s.deliver(ctx, ref, msg, func(resp protomessage.ProtoMessage, err error) { ch <- result{resp, err} }); err != nil {
+		return nil, err
+	}
+	select {
+	case r := <-ch:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *actorSystem) tell(ref ActorRef, msg protomessage.ProtoMessage) error {
+	conn, err := s.remote(ref.Kind, ref.Key)
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		return s.deliver(context.Background(), ref, msg, nil)
+	}
+	typ, payload, err := marshalMessage(msg)
+	if err != nil {
+		return err
+	}
+	return conn.SendTypePb(packet.Request, &N2MRequest{Kind: ref.Kind, Key: ref.Key, Type: typ, Payload: payload})
+}
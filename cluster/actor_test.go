@@ -0,0 +1,134 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"infra-foundation/model"
+	"infra-foundation/packet"
+	"infra-foundation/protomessage"
+	"infra-foundation/scheduler"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type counterActor struct {
+	key         string
+	count       int64
+	deactivated chan string
+}
+
+func (a *counterActor) OnActivate(ref ActorRef) error { return nil }
+func (a *counterActor) OnDeactivate()                 { a.deactivated <- a.key }
+
+func (a *counterActor) Receive(ctx context.Context, msg protomessage.ProtoMessage) (protomessage.ProtoMessage, error) {
+	a.count += msg.(*N2MOnSessionClose).SessionID
+	return &N2MOnSessionClose{SessionID: a.count}, nil
+}
+
+// loopback serves the requests it sends itself, standing in for a remote node.
+type loopback struct{}
+
+func (l *loopback) SendData([]byte) error { return nil }
+
+func (l *loopback) SendTypePb(typ packet.Type, pb protomessage.ProtoMessage) error {
+	switch typ {
+	case packet.Request:
+		return defaultRPC.serve(l, pb.(*N2MRequest))
+	case packet.Response:
+		return defaultRPC.resolve(pb.(*N2MResponse))
+	}
+	return nil
+}
+
+func TestActorAskAndIdle(t *testing.T) {
+	mm := model.NewModelManager()
+	defer mm.Stop()
+	actors := defaultActors
+	deactivated := make(chan string, 4)
+	clock := scheduler.NewFakeClock(time.Now())
+	err := actors.register(mm, "counter", func(key string) Actor {
+		return &counterActor{key: key, deactivated: deactivated}
+	}, ActorOptions{IdleTimeout: time.Minute, Shards: 2, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		actors.mu.Lock()
+		delete(actors.kinds, "counter")
+		actors.mu.Unlock()
+	})
+
+	ctx := context.Background()
+	for i := range 3 {
+		resp, err := actors.ask(ctx, ActorOf("counter", "a"), &N2MOnSessionClose{SessionID: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.(*N2MOnSessionClose).SessionID; got != int64(2*(i+1)) {
+			t.Fatalf("count = %d", got)
+		}
+	}
+
+	typ, payload, _ := marshalMessage(&N2MOnSessionClose{SessionID: 5})
+	resp, err := defaultRPC.call(ctx, &loopback{}, &N2MRequest{Kind: "counter", Key: "a", Type: typ, Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.(*N2MOnSessionClose).SessionID; got != 11 {
+		t.Fatalf("remote count = %d", got)
+	}
+	if _, err = defaultRPC.call(ctx, &loopback{}, &N2MRequest{Kind: "missing", Type: typ, Payload: payload}); err == nil {
+		t.Fatal("expected error for unknown kind")
+	}
+
+	k, _ := actors.kind("counter")
+	k.collectIdle()
+	if keys := k.keys(); len(keys) != 1 {
+		t.Fatalf("active keys before idle timeout = %v", keys)
+	}
+	clock.Advance(time.Minute)
+	k.collectIdle()
+	select {
+	case key := <-deactivated:
+		if key != "a" {
+			t.Fatalf("deactivated %q", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("idle actor was not deactivated")
+	}
+	if keys := k.keys(); len(keys) != 0 {
+		t.Fatalf("active keys = %v", keys)
+	}
+}
+
+func TestHashRingStable(t *testing.T) {
+	before := newHashRing([]string{"1", "2", "3"})
+	after := newHashRing([]string{"1", "2", "3", "4"})
+	moved := 0
+	for i := range 1000 {
+		key := strconv.Itoa(i)
+		a, _ := before.owner(key)
+		b, _ := after.owner(key)
+		if a != b {
+			if b != "4" {
+				t.Fatalf("key %s moved from %s to %s", key, a, b)
+			}
+			moved++
+		}
+	}
+	if moved == 0 || moved > 400 {
+		t.Fatalf("moved = %d", moved)
+	}
+}
+
+func TestActorRemoteBadOwner(t *testing.T) {
+	agent := defaultNodeAgent
+	defaultNodeAgent = newNodeAgent()
+	defer func() { defaultNodeAgent = agent }()
+	defaultNodeAgent.setNode("GAME", "1", "", false)
+	s := &actorSystem{kinds: map[string]*actorKind{}, rings: map[string]*hashRing{"room": newHashRing([]string{"game-2"})}}
+	if _, err := s.remote("room", "7"); !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("err = %v", err)
+	}
+}
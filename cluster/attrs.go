@@ -0,0 +1,34 @@
+package cluster
+
+import (
+	"errors"
+	"infra-foundation/packet"
+	"infra-foundation/session"
+)
+
+func (c *Connection) ReplicateAttrs(attrs map[string][]byte) error {
+	return replicateAttrs(c, attrs)
+}
+
+func (a *acceptor) ReplicateAttrs(attrs map[string][]byte) error {
+	return replicateAttrs(a, attrs)
+}
+
+// replicateAttrs sends attrs to every other node s is bound to, on the
+// bind packet that also carries its server bindings.
+func replicateAttrs(s session.Session, attrs map[string][]byte) error {
+	pb := &N2MOnSessionBindServer{SessionID: s.ID(), UID: s.UID(), Attrs: attrs}
+	var errs []error
+	for _, id := range s.Servers() {
+		if defaultNodeAgent.node != nil && id == defaultNodeAgent.node.Id {
+			continue
+		}
+		conn, err := defaultNodeAgent.nodeConn(id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, conn.SendTypePb(packet.BindConnection, pb))
+	}
+	return errors.Join(errs...)
+}
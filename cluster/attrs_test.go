@@ -0,0 +1,70 @@
+package cluster
+
+import (
+	"infra-foundation/protomessage"
+	"infra-foundation/session"
+	"testing"
+)
+
+func TestReplicatedAttrs(t *testing.T) {
+	lang := session.NewReplicatedAttr[string]("lang")
+	room := session.NewAttr[int]("room")
+	gate := session.NewNetworkEntities(1, 9)
+	sess := testGateSession{gate}
+	if err := lang.Set(sess, "de"); err != nil {
+		t.Fatal(err)
+	}
+	if err := room.Set(sess, 42); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := room.Get(sess); v != 42 {
+		t.Fatalf("room = %d", v)
+	}
+
+	svr := NewServer()
+	bindAcceptor(svr.ConnManager(), &N2MOnSessionBindServer{SessionID: 91, UID: 9, Servers: map[string]string{"GATE": "1"}, Attrs: gate.Attrs().Replicated()})
+	conn, ok := svr.ConnManager().GetByID(91)
+	if !ok {
+		t.Fatal("acceptor not created")
+	}
+	if v, ok := lang.Get(conn); !ok || v != "de" {
+		t.Fatalf("lang = %q %v", v, ok)
+	}
+	if _, ok := room.Get(conn); ok {
+		t.Fatal("local attribute replicated")
+	}
+
+	bindAcceptor(svr.ConnManager(), &N2MOnSessionBindServer{SessionID: 91, Attrs: map[string][]byte{"lang": nil}})
+	if _, ok := lang.Get(conn); ok {
+		t.Fatal("deleted attribute still readable")
+	}
+	if bindAcceptor(svr.ConnManager(), &N2MOnSessionBindServer{SessionID: 92, Attrs: map[string][]byte{"lang": []byte(`"fr"`)}}) != nil {
+		t.Fatal("attribute update created a session")
+	}
+}
+
+func TestReplicatedAttrAfterLocal(t *testing.T) {
+	lang := session.NewReplicatedAttr[string]("lang")
+	room := session.NewAttr[int]("room")
+	svr := NewServer()
+	conn := bindAcceptor(svr.ConnManager(), &N2MOnSessionBindServer{SessionID: 93, UID: 9, Servers: map[string]string{"GATE": "1"}})
+	if err := room.Set(conn, 7); err != nil {
+		t.Fatal(err)
+	}
+	bindAcceptor(svr.ConnManager(), &N2MOnSessionBindServer{SessionID: 93, Attrs: map[string][]byte{"lang": []byte(`"it"`)}})
+	if v, ok := lang.Get(conn); !ok || v != "it" {
+		t.Fatalf("lang = %q %v", v, ok)
+	}
+	if v, _ := room.Get(conn); v != 7 {
+		t.Fatalf("room = %d", v)
+	}
+	if got := conn.Attrs().Replicated(); len(got) != 1 || string(got["lang"]) != `"it"` {
+		t.Fatalf("replicated = %v", got)
+	}
+}
+
+type testGateSession struct{ *session.NetworkEntities }
+
+func (testGateSession) Send(protomessage.ProtoMessage) error                      { return nil }
+func (testGateSession) Notify([]session.Session, protomessage.ProtoMessage) error { return nil }
+func (testGateSession) Close() error                                              { return nil }
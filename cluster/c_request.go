@@ -37,6 +37,9 @@ func (c *ClientRequest) OnRequest(ctx context.Context, connection netpoll.Connec
 	r2, err := c.PackCodec.NextPacket(connection.Reader())
 	if err != nil {
 		logx.Err.Printf("[ClientRequest/OnRequest] NextPacket error %v", err)
+		if _, ok := errors.AsType[*packet.ProtocolError](err); ok {
+			c.violate(err, true)
+		}
 		return fmt.Errorf("[ClientRequest/OnRequest] Peek error %v", err)
 	}
 	if r2 == nil {
@@ -46,9 +49,16 @@ func (c *ClientRequest) OnRequest(ctx context.Context, connection netpoll.Connec
 		pk, err := c.PackCodec.Unpack1(r2)
 		if err != nil {
 			logx.Err.Printf("[ClientRequest/OnRequest] Unpack error %v", err)
+			c.violate(err, false)
+			return
+		}
+		if err = c.PackCodec.Accept(pk, c.SendData); err != nil {
+			logx.Err.Printf("[ClientRequest/OnRequest] Accept error %v", err)
+			c.violate(err, true)
+			pk.Free()
 			return
 		}
-		if err = c.onMessage(pk.Type(), pk.ID(), pk.SID(), pk.Data()); err != nil {
+		if err = c.onMessage(pk.Type(), pk.ID(), pk.SID(), pk.Seq(), pk.Data()); err != nil {
 			logx.Err.Println(err)
 		}
 		pk.Free()
@@ -58,7 +68,7 @@ func (c *ClientRequest) OnRequest(ctx context.Context, connection netpoll.Connec
 	return err
 }
 
-func (c *ClientRequest) onMessage(typ packet.Type, id int32, sid int64, bdata []byte) (err error) {
+func (c *ClientRequest) onMessage(typ packet.Type, id int32, sid int64, seq uint32, bdata []byte) (err error) {
 	switch typ {
 	case packet.Connection:
 		var pb = &M2NOnConnection{}
@@ -82,15 +92,8 @@ func (c *ClientRequest) onMessage(typ packet.Type, id int32, sid int64, bdata []
 		if err := proto.Unmarshal(bdata, &pb); err != nil {
 			return fmt.Errorf("[ClientRequest/onMessage] Type[%d] ConnID[%d] Unmarshal %w", typ, c.ID(), err)
 		}
-		conn, ok := c.connManager.GetByID(pb.SessionID)
-		if !ok {
-			conn = newAcceptor(session.NewNetworkEntities(pb.SessionID, pb.UID), defaultNodeAgent.svr)
-			c.connManager.StoreSession(conn)
-		}
-		for name, id := range pb.GetServers() {
-			conn.BindServers(name, id)
-		}
-		logx.Dbg.Printf("[ClientRequest/onMessage] Type[%d] ConnID[%d] SessionID: %d %v", typ, c.ID(), pb.SessionID, conn.Servers())
+		bindAcceptor(c.connManager, &pb)
+		logx.Dbg.Printf("[ClientRequest/onMessage] Type[%d] ConnID[%d] SessionID: %d %v", typ, c.ID(), pb.SessionID, pb.GetServers())
 	case packet.InternalData:
 		if !model.IsLocalHandler(id) {
 			return fmt.Errorf("[ClientRequest/onMessage] Type[%d] ConnID[%d] MessageID: %d not found", typ, c.ID(), id)
@@ -99,7 +102,7 @@ func (c *ClientRequest) onMessage(typ packet.Type, id int32, sid int64, bdata []
 		if !ok {
 			return fmt.Errorf("[ClientRequest/onMessage] Type[%d] ConnID[%d] SessionID: %d not found", typ, c.ID(), sid)
 		}
-		err = c.modelManager.DispatchAsync(conn, id, bdata)
+		err = c.modelManager.DispatchAsync(conn, id, seq, bdata)
 	case packet.ClientData:
 		conn, ok := c.connManager.GetByID(sid)
 		if !ok {
@@ -110,11 +113,26 @@ func (c *ClientRequest) onMessage(typ packet.Type, id int32, sid int64, bdata []
 			return fmt.Errorf("[ClientConnection/onMessage] Type[%d] 反射 SendData", typ)
 		}
 		err = conn1.SendData(bdata)
+	case packet.Request:
+		var pb N2MRequest
+		if err := proto.Unmarshal(bdata, &pb); err != nil {
+			return fmt.Errorf("[ClientRequest/onMessage] Type[%d] ConnID[%d] Unmarshal %w", typ, c.ID(), err)
+		}
+		err = defaultRPC.serve(c, &pb)
+	case packet.Response:
+		var pb N2MResponse
+		if err := proto.Unmarshal(bdata, &pb); err != nil {
+			return fmt.Errorf("[ClientRequest/onMessage] Type[%d] ConnID[%d] Unmarshal %w", typ, c.ID(), err)
+		}
+		err = defaultRPC.resolve(&pb)
 	case packet.NotifyData:
 		var pb N2MNotify
 		if err := proto.Unmarshal(bdata, &pb); err != nil {
 			return fmt.Errorf("[ClientRequest/onMessage] Type[%d] ConnID[%d] Unmarshal %w", typ, c.ID(), err)
 		}
+		if pb.Group != "" {
+			return defaultGroups.deliver(pb.Group, pb.Plyload)
+		}
 		if len(pb.SessionID) == 0 {
 			return c.connManager.Range(func(s session.Session) error {
 				conn1, ok := s.(sender)
@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"maps"
+	"math/rand"
+	"slices"
+	"sync/atomic"
+)
+
+// RoutingRule sends the sessions it selects to the nodes of Service matching
+// Version and Labels. A session is selected when its UID is listed in UIDs,
+// or otherwise with probability Percent/100 when it is first bound to the
+// service. Sessions stay on the node they were bound to.
+type RoutingRule struct {
+	Service string
+	Version string
+	Labels  map[string]string
+	UIDs    []int64
+	Percent float64
+}
+
+func (r *RoutingRule) matches(nd *node) bool {
+	if r.Version != "" && nd.Version != r.Version {
+		return false
+	}
+	for k, v := range r.Labels {
+		if nd.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *RoutingRule) selects(uid int64) bool {
+	if slices.Contains(r.UIDs, uid) {
+		return true
+	}
+	return r.Percent > 0 && rand.Float64()*100 < r.Percent
+}
+
+var routingRules atomic.Pointer[[]RoutingRule]
+
+// SetRoutingRules replaces the routing rules. Rules are tried in order and
+// the first one selecting a session decides its nodes.
+func SetRoutingRules(rules ...RoutingRule) {
+	routingRules.Store(&rules)
+}
+
+// SetNodeVersion sets the version advertised by the local node. It must be
+// called before RegisterService.
+func SetNodeVersion(v string) {
+	defaultNodeAgent.m.Lock()
+	defaultNodeAgent.version = v
+	defaultNodeAgent.m.Unlock()
+}
+
+// SetNodeLabels sets the labels advertised by the local node. It must be
+// called before RegisterService.
+func SetNodeLabels(labels map[string]string) {
+	defaultNodeAgent.m.Lock()
+	defaultNodeAgent.labels = maps.Clone(labels)
+	defaultNodeAgent.m.Unlock()
+}
+
+// candidates returns the nodes of a service a new session may be bound to.
+// A session selected by a rule goes to the nodes matching it; any other
+// session avoids the nodes targeted by the rules of the service. Either set
+// falls back to every node when empty.
+func candidates(name string, uid int64, nodes []*node) []*node {
+	p := routingRules.Load()
+	if p == nil {
+		return nodes
+	}
+	var rules []*RoutingRule
+	for i := range *p {
+		if (*p)[i].Service == name {
+			rules = append(rules, &(*p)[i])
+		}
+	}
+	for _, r := range rules {
+		if !r.selects(uid) {
+			continue
+		}
+		if matched := slices.DeleteFunc(slices.Clone(nodes), func(nd *node) bool { return !r.matches(nd) }); len(matched) > 0 {
+			return matched
+		}
+		return nodes
+	}
+	rest := slices.DeleteFunc(slices.Clone(nodes), func(nd *node) bool {
+		return slices.ContainsFunc(rules, func(r *RoutingRule) bool { return r.matches(nd) })
+	})
+	if len(rest) > 0 {
+		return rest
+	}
+	return nodes
+}
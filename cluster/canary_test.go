@@ -0,0 +1,32 @@
+package cluster
+
+import "testing"
+
+func TestCanaryCandidates(t *testing.T) {
+	v1 := &node{Id: "1", Name: "GAME", Version: "1"}
+	v2 := &node{Id: "2", Name: "GAME", Version: "2", Labels: map[string]string{"track": "canary"}}
+	nodes := []*node{v1, v2}
+	defer SetRoutingRules()
+
+	SetRoutingRules(RoutingRule{Service: "GAME", Version: "2", UIDs: []int64{7}})
+	if c := candidates("GAME", 7, nodes); len(c) != 1 || c[0] != v2 {
+		t.Fatalf("listed uid -> %v", c)
+	}
+	for range 100 {
+		if c := candidates("GAME", 8, nodes); len(c) != 1 || c[0] != v1 {
+			t.Fatalf("other uid -> %v", c)
+		}
+	}
+	if c := candidates("CHAT", 7, nodes); len(c) != 2 {
+		t.Fatalf("other service -> %v", c)
+	}
+
+	SetRoutingRules(RoutingRule{Service: "GAME", Labels: map[string]string{"track": "canary"}, Percent: 100})
+	if c := candidates("GAME", 8, nodes); len(c) != 1 || c[0] != v2 {
+		t.Fatalf("percent -> %v", c)
+	}
+	SetRoutingRules(RoutingRule{Service: "GAME", Version: "3", Percent: 100})
+	if c := candidates("GAME", 8, nodes); len(c) != 2 {
+		t.Fatalf("no matching node -> %v", c)
+	}
+}
@@ -32,9 +32,14 @@ func (c *ClientConnection) DialConnection(addr string) error {
 		return err
 	}
 	c.Connection = NewConnection(conn, 1, -1)
+	c.Connection.SetClock(c.scheduler.Clock())
 	c.SetOnRequest(c.ClientRequest.OnRequest)
 	c.timerID, _ = c.scheduler.PushEvery(c.heartbeatTime, c.sendHeartbeat)
-	return nil
+	handshake, err := c.Handshake()
+	if err != nil {
+		return err
+	}
+	return c.SendData(handshake)
 }
 
 func (c *ClientConnection) Close() error {
@@ -49,7 +54,7 @@ func (c *ClientConnection) Close() error {
 }
 
 func (c *ClientConnection) sendHeartbeat() {
-	now := time.Now().Unix()
+	now := c.scheduler.Clock().Now().Unix()
 	if c.HeartbeatAt()+int64(c.heartbeatTime.Seconds()) > now {
 		return
 	}
@@ -22,6 +22,7 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// @id 1
 type N2MSend struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SessionID     int64                  `protobuf:"varint,1,opt,name=SessionID,proto3" json:"SessionID,omitempty"`
@@ -207,6 +208,7 @@ type N2MOnSessionBindServer struct {
 	SessionID     int64                  `protobuf:"varint,1,opt,name=SessionID,proto3" json:"SessionID,omitempty"`
 	UID           int64                  `protobuf:"varint,2,opt,name=UID,proto3" json:"UID,omitempty"`
 	Servers       map[string]string      `protobuf:"bytes,3,rep,name=Servers,proto3" json:"Servers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Attrs         map[string][]byte      `protobuf:"bytes,4,rep,name=Attrs,proto3" json:"Attrs,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -262,6 +264,13 @@ func (x *N2MOnSessionBindServer) GetServers() map[string]string {
 	return nil
 }
 
+func (x *N2MOnSessionBindServer) GetAttrs() map[string][]byte {
+	if x != nil {
+		return x.Attrs
+	}
+	return nil
+}
+
 type N2MOnSessionClose struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SessionID     int64                  `protobuf:"varint,1,opt,name=SessionID,proto3" json:"SessionID,omitempty"`
@@ -306,10 +315,12 @@ func (x *N2MOnSessionClose) GetSessionID() int64 {
 	return 0
 }
 
+// @id 9
 type N2MNotify struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SessionID     []int64                `protobuf:"varint,1,rep,packed,name=SessionID,proto3" json:"SessionID,omitempty"`
 	Plyload       []byte                 `protobuf:"bytes,2,opt,name=Plyload,proto3" json:"Plyload,omitempty"`
+	Group         string                 `protobuf:"bytes,3,opt,name=Group,proto3" json:"Group,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -358,6 +369,535 @@ func (x *N2MNotify) GetPlyload() []byte {
 	return nil
 }
 
+func (x *N2MNotify) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+// @id 6
+type N2MRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seq           uint64                 `protobuf:"varint,1,opt,name=Seq,proto3" json:"Seq,omitempty"`
+	Kind          string                 `protobuf:"bytes,2,opt,name=Kind,proto3" json:"Kind,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=Key,proto3" json:"Key,omitempty"`
+	Type          string                 `protobuf:"bytes,4,opt,name=Type,proto3" json:"Type,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,5,opt,name=Payload,proto3" json:"Payload,omitempty"`
+	Deadline      int64                  `protobuf:"varint,6,opt,name=Deadline,proto3" json:"Deadline,omitempty"`
+	Model         string                 `protobuf:"bytes,7,opt,name=Model,proto3" json:"Model,omitempty"`
+	Cancel        bool                   `protobuf:"varint,8,opt,name=Cancel,proto3" json:"Cancel,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *N2MRequest) Reset() {
+	*x = N2MRequest{}
+	mi := &file_cluster_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *N2MRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*N2MRequest) ProtoMessage() {}
+
+func (x *N2MRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use N2MRequest.ProtoReflect.Descriptor instead.
+func (*N2MRequest) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *N2MRequest) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *N2MRequest) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *N2MRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *N2MRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *N2MRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *N2MRequest) GetDeadline() int64 {
+	if x != nil {
+		return x.Deadline
+	}
+	return 0
+}
+
+func (x *N2MRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *N2MRequest) GetCancel() bool {
+	if x != nil {
+		return x.Cancel
+	}
+	return false
+}
+
+type N2MResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seq           uint64                 `protobuf:"varint,1,opt,name=Seq,proto3" json:"Seq,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=Type,proto3" json:"Type,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,3,opt,name=Payload,proto3" json:"Payload,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=Error,proto3" json:"Error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *N2MResponse) Reset() {
+	*x = N2MResponse{}
+	mi := &file_cluster_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *N2MResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*N2MResponse) ProtoMessage() {}
+
+func (x *N2MResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use N2MResponse.ProtoReflect.Descriptor instead.
+func (*N2MResponse) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *N2MResponse) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *N2MResponse) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *N2MResponse) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *N2MResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type M2CError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          int32                  `protobuf:"varint,1,opt,name=Code,proto3" json:"Code,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=Message,proto3" json:"Message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *M2CError) Reset() {
+	*x = M2CError{}
+	mi := &file_cluster_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *M2CError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*M2CError) ProtoMessage() {}
+
+func (x *M2CError) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use M2CError.ProtoReflect.Descriptor instead.
+func (*M2CError) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *M2CError) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *M2CError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// @id 10
+type N2MSessionExport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionID     int64                  `protobuf:"varint,1,opt,name=SessionID,proto3" json:"SessionID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *N2MSessionExport) Reset() {
+	*x = N2MSessionExport{}
+	mi := &file_cluster_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *N2MSessionExport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*N2MSessionExport) ProtoMessage() {}
+
+func (x *N2MSessionExport) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use N2MSessionExport.ProtoReflect.Descriptor instead.
+func (*N2MSessionExport) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *N2MSessionExport) GetSessionID() int64 {
+	if x != nil {
+		return x.SessionID
+	}
+	return 0
+}
+
+type N2MSessionState struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionID     int64                  `protobuf:"varint,1,opt,name=SessionID,proto3" json:"SessionID,omitempty"`
+	Models        map[string][]byte      `protobuf:"bytes,2,rep,name=Models,proto3" json:"Models,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *N2MSessionState) Reset() {
+	*x = N2MSessionState{}
+	mi := &file_cluster_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *N2MSessionState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*N2MSessionState) ProtoMessage() {}
+
+func (x *N2MSessionState) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use N2MSessionState.ProtoReflect.Descriptor instead.
+func (*N2MSessionState) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *N2MSessionState) GetSessionID() int64 {
+	if x != nil {
+		return x.SessionID
+	}
+	return 0
+}
+
+func (x *N2MSessionState) GetModels() map[string][]byte {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+type N2MSessionImport struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Bind          *N2MOnSessionBindServer `protobuf:"bytes,1,opt,name=Bind,proto3" json:"Bind,omitempty"`
+	Models        map[string][]byte       `protobuf:"bytes,2,rep,name=Models,proto3" json:"Models,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *N2MSessionImport) Reset() {
+	*x = N2MSessionImport{}
+	mi := &file_cluster_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *N2MSessionImport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*N2MSessionImport) ProtoMessage() {}
+
+func (x *N2MSessionImport) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use N2MSessionImport.ProtoReflect.Descriptor instead.
+func (*N2MSessionImport) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *N2MSessionImport) GetBind() *N2MOnSessionBindServer {
+	if x != nil {
+		return x.Bind
+	}
+	return nil
+}
+
+func (x *N2MSessionImport) GetModels() map[string][]byte {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+type N2MGroup struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=Group,proto3" json:"Group,omitempty"`
+	SessionID     []int64                `protobuf:"varint,2,rep,packed,name=SessionID,proto3" json:"SessionID,omitempty"`
+	Leave         bool                   `protobuf:"varint,3,opt,name=Leave,proto3" json:"Leave,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *N2MGroup) Reset() {
+	*x = N2MGroup{}
+	mi := &file_cluster_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *N2MGroup) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*N2MGroup) ProtoMessage() {}
+
+func (x *N2MGroup) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use N2MGroup.ProtoReflect.Descriptor instead.
+func (*N2MGroup) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *N2MGroup) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *N2MGroup) GetSessionID() []int64 {
+	if x != nil {
+		return x.SessionID
+	}
+	return nil
+}
+
+func (x *N2MGroup) GetLeave() bool {
+	if x != nil {
+		return x.Leave
+	}
+	return false
+}
+
+type N2MPublish struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=Topic,proto3" json:"Topic,omitempty"`
+	Publisher     string                 `protobuf:"bytes,2,opt,name=Publisher,proto3" json:"Publisher,omitempty"`
+	Epoch         int64                  `protobuf:"varint,3,opt,name=Epoch,proto3" json:"Epoch,omitempty"`
+	Seq           uint64                 `protobuf:"varint,4,opt,name=Seq,proto3" json:"Seq,omitempty"`
+	Ordered       bool                   `protobuf:"varint,5,opt,name=Ordered,proto3" json:"Ordered,omitempty"`
+	Type          string                 `protobuf:"bytes,6,opt,name=Type,proto3" json:"Type,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,7,opt,name=Payload,proto3" json:"Payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *N2MPublish) Reset() {
+	*x = N2MPublish{}
+	mi := &file_cluster_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *N2MPublish) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*N2MPublish) ProtoMessage() {}
+
+func (x *N2MPublish) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use N2MPublish.ProtoReflect.Descriptor instead.
+func (*N2MPublish) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *N2MPublish) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *N2MPublish) GetPublisher() string {
+	if x != nil {
+		return x.Publisher
+	}
+	return ""
+}
+
+func (x *N2MPublish) GetEpoch() int64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+func (x *N2MPublish) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *N2MPublish) GetOrdered() bool {
+	if x != nil {
+		return x.Ordered
+	}
+	return false
+}
+
+func (x *N2MPublish) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *N2MPublish) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
 var File_cluster_proto protoreflect.FileDescriptor
 
 const file_cluster_proto_rawDesc = "" +
@@ -374,19 +914,70 @@ const file_cluster_proto_rawDesc = "" +
 	"\x0fM2NOnConnection\x12\x0e\n" +
 	"\x02ID\x18\x01 \x01(\tR\x02ID\x12\x12\n" +
 	"\x04Name\x18\x02 \x01(\tR\x04Name\x12\x1a\n" +
-	"\bFrontend\x18\x03 \x01(\bR\bFrontend\"\xcc\x01\n" +
+	"\bFrontend\x18\x03 \x01(\bR\bFrontend\"\xc8\x02\n" +
 	"\x16N2MOnSessionBindServer\x12\x1c\n" +
 	"\tSessionID\x18\x01 \x01(\x03R\tSessionID\x12\x10\n" +
 	"\x03UID\x18\x02 \x01(\x03R\x03UID\x12F\n" +
-	"\aServers\x18\x03 \x03(\v2,.cluster.N2MOnSessionBindServer.ServersEntryR\aServers\x1a:\n" +
+	"\aServers\x18\x03 \x03(\v2,.cluster.N2MOnSessionBindServer.ServersEntryR\aServers\x12@\n" +
+	"\x05Attrs\x18\x04 \x03(\v2*.cluster.N2MOnSessionBindServer.AttrsEntryR\x05Attrs\x1a:\n" +
 	"\fServersEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"1\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a8\n" +
+	"\n" +
+	"AttrsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\"1\n" +
 	"\x11N2MOnSessionClose\x12\x1c\n" +
-	"\tSessionID\x18\x01 \x01(\x03R\tSessionID\"C\n" +
+	"\tSessionID\x18\x01 \x01(\x03R\tSessionID\"Y\n" +
 	"\tN2MNotify\x12\x1c\n" +
 	"\tSessionID\x18\x01 \x03(\x03R\tSessionID\x12\x18\n" +
-	"\aPlyload\x18\x02 \x01(\fR\aPlyloadB\fZ\n" +
+	"\aPlyload\x18\x02 \x01(\fR\aPlyload\x12\x14\n" +
+	"\x05Group\x18\x03 \x01(\tR\x05Group\"\xbc\x01\n" +
+	"\n" +
+	"N2MRequest\x12\x10\n" +
+	"\x03Seq\x18\x01 \x01(\x04R\x03Seq\x12\x12\n" +
+	"\x04Kind\x18\x02 \x01(\tR\x04Kind\x12\x10\n" +
+	"\x03Key\x18\x03 \x01(\tR\x03Key\x12\x12\n" +
+	"\x04Type\x18\x04 \x01(\tR\x04Type\x12\x18\n" +
+	"\aPayload\x18\x05 \x01(\fR\aPayload\x12\x1a\n" +
+	"\bDeadline\x18\x06 \x01(\x03R\bDeadline\x12\x14\n" +
+	"\x05Model\x18\a \x01(\tR\x05Model\x12\x16\n" +
+	"\x06Cancel\x18\b \x01(\bR\x06Cancel\"c\n" +
+	"\vN2MResponse\x12\x10\n" +
+	"\x03Seq\x18\x01 \x01(\x04R\x03Seq\x12\x12\n" +
+	"\x04Type\x18\x02 \x01(\tR\x04Type\x12\x18\n" +
+	"\aPayload\x18\x03 \x01(\fR\aPayload\x12\x14\n" +
+	"\x05Error\x18\x04 \x01(\tR\x05Error\"8\n" +
+	"\bM2CError\x12\x12\n" +
+	"\x04Code\x18\x01 \x01(\x05R\x04Code\x12\x18\n" +
+	"\aMessage\x18\x02 \x01(\tR\aMessage\"0\n" +
+	"\x10N2MSessionExport\x12\x1c\n" +
+	"\tSessionID\x18\x01 \x01(\x03R\tSessionID\"\xa8\x01\n" +
+	"\x0fN2MSessionState\x12\x1c\n" +
+	"\tSessionID\x18\x01 \x01(\x03R\tSessionID\x12<\n" +
+	"\x06Models\x18\x02 \x03(\v2$.cluster.N2MSessionState.ModelsEntryR\x06Models\x1a9\n" +
+	"\vModelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\"\xc1\x01\n" +
+	"\x10N2MSessionImport\x123\n" +
+	"\x04Bind\x18\x01 \x01(\v2\x1f.cluster.N2MOnSessionBindServerR\x04Bind\x12=\n" +
+	"\x06Models\x18\x02 \x03(\v2%.cluster.N2MSessionImport.ModelsEntryR\x06Models\x1a9\n" +
+	"\vModelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\"T\n" +
+	"\bN2MGroup\x12\x14\n" +
+	"\x05Group\x18\x01 \x01(\tR\x05Group\x12\x1c\n" +
+	"\tSessionID\x18\x02 \x03(\x03R\tSessionID\x12\x14\n" +
+	"\x05Leave\x18\x03 \x01(\bR\x05Leave\"\xb0\x01\n" +
+	"\n" +
+	"N2MPublish\x12\x14\n" +
+	"\x05Topic\x18\x01 \x01(\tR\x05Topic\x12\x1c\n" +
+	"\tPublisher\x18\x02 \x01(\tR\tPublisher\x12\x14\n" +
+	"\x05Epoch\x18\x03 \x01(\x03R\x05Epoch\x12\x10\n" +
+	"\x03Seq\x18\x04 \x01(\x04R\x03Seq\x12\x18\n" +
+	"\aOrdered\x18\x05 \x01(\bR\aOrdered\x12\x12\n" +
+	"\x04Type\x18\x06 \x01(\tR\x04Type\x12\x18\n" +
+	"\aPayload\x18\a \x01(\fR\aPayloadB\fZ\n" +
 	"./;clusterb\x06proto3"
 
 var (
@@ -401,7 +992,7 @@ func file_cluster_proto_rawDescGZIP() []byte {
 	return file_cluster_proto_rawDescData
 }
 
-var file_cluster_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_cluster_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
 var file_cluster_proto_goTypes = []any{
 	(*N2MSend)(nil),                // 0: cluster.N2MSend
 	(*N2MOnConnection)(nil),        // 1: cluster.N2MOnConnection
@@ -409,15 +1000,30 @@ var file_cluster_proto_goTypes = []any{
 	(*N2MOnSessionBindServer)(nil), // 3: cluster.N2MOnSessionBindServer
 	(*N2MOnSessionClose)(nil),      // 4: cluster.N2MOnSessionClose
 	(*N2MNotify)(nil),              // 5: cluster.N2MNotify
-	nil,                            // 6: cluster.N2MOnSessionBindServer.ServersEntry
+	(*N2MRequest)(nil),             // 6: cluster.N2MRequest
+	(*N2MResponse)(nil),            // 7: cluster.N2MResponse
+	(*M2CError)(nil),               // 8: cluster.M2CError
+	(*N2MSessionExport)(nil),       // 9: cluster.N2MSessionExport
+	(*N2MSessionState)(nil),        // 10: cluster.N2MSessionState
+	(*N2MSessionImport)(nil),       // 11: cluster.N2MSessionImport
+	(*N2MGroup)(nil),               // 12: cluster.N2MGroup
+	(*N2MPublish)(nil),             // 13: cluster.N2MPublish
+	nil,                            // 14: cluster.N2MOnSessionBindServer.ServersEntry
+	nil,                            // 15: cluster.N2MOnSessionBindServer.AttrsEntry
+	nil,                            // 16: cluster.N2MSessionState.ModelsEntry
+	nil,                            // 17: cluster.N2MSessionImport.ModelsEntry
 }
 var file_cluster_proto_depIdxs = []int32{
-	6, // 0: cluster.N2MOnSessionBindServer.Servers:type_name -> cluster.N2MOnSessionBindServer.ServersEntry
-	1, // [1:1] is the sub-list for method output_type
-	1, // [1:1] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	14, // 0: cluster.N2MOnSessionBindServer.Servers:type_name -> cluster.N2MOnSessionBindServer.ServersEntry
+	15, // 1: cluster.N2MOnSessionBindServer.Attrs:type_name -> cluster.N2MOnSessionBindServer.AttrsEntry
+	16, // 2: cluster.N2MSessionState.Models:type_name -> cluster.N2MSessionState.ModelsEntry
+	3,  // 3: cluster.N2MSessionImport.Bind:type_name -> cluster.N2MOnSessionBindServer
+	17, // 4: cluster.N2MSessionImport.Models:type_name -> cluster.N2MSessionImport.ModelsEntry
+	5,  // [5:5] is the sub-list for method output_type
+	5,  // [5:5] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_cluster_proto_init() }
@@ -431,7 +1037,7 @@ func file_cluster_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_cluster_proto_rawDesc), len(file_cluster_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   18,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
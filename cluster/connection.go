@@ -7,34 +7,47 @@ import (
 	"infra-foundation/packet"
 	"infra-foundation/protomessage"
 	"infra-foundation/queue"
+	"infra-foundation/scheduler"
 	"infra-foundation/session"
 	"sync"
 	"sync/atomic"
-	"time"
 
 	"github.com/cloudwego/netpoll"
 	"google.golang.org/protobuf/proto"
 )
 
+// maxWriteBatch bounds the packets flushed by one write.
+const maxWriteBatch = 128
+
+// outbound is a queued write; buf, when set, is freed once data is written.
+type outbound struct {
+	data []byte
+	buf  *packet.Buffer
+}
+
 type Connection struct {
 	netpoll.Connection
 	*session.NetworkEntities
 	*packet.PackCodec
-	writeQ            *queue.Queue[[]byte]
+	writeQ            *queue.Queue[outbound]
 	writeCond         *sync.Cond
 	closed            atomic.Bool
 	lastHeartBeatTime atomic.Int64
+	violations        atomic.Int32
+	clock             scheduler.Clock
 	wg                sync.WaitGroup
 }
 
 func NewConnection(conn netpoll.Connection, id, uid int64) *Connection {
 	c := &Connection{
 		Connection:      conn,
-		writeQ:          queue.New[[]byte](),
+		writeQ:          queue.New[outbound](),
 		writeCond:       sync.NewCond(&sync.Mutex{}),
 		NetworkEntities: session.NewNetworkEntities(id, uid),
 		PackCodec:       packet.NewPackCodec(),
+		clock:           scheduler.SystemClock,
 	}
+	c.PackCodec.EnableCompression(packet.DefaultCompressThreshold)
 	c.wg.Go(c.writeLoop)
 	return c
 }
@@ -55,22 +68,27 @@ func (c *Connection) SetHeartbeatAt(now int64) {
 	c.lastHeartBeatTime.Store(now)
 }
 
+func (c *Connection) SetClock(clock scheduler.Clock) {
+	c.clock = clock
+}
+
 func (c *Connection) RefreshHeartbeat() {
-	c.SetHeartbeatAt(time.Now().Unix())
+	c.SetHeartbeatAt(c.clock.Now().Unix())
 }
 
 func (c *Connection) Send(pb protomessage.ProtoMessage) error {
 	if c.IsClosed() {
 		return errors.New("[Connection/Send] connection closed")
 	}
-	if defaultNodeAgent.node.Name == pb.NodeName() {
+	name, ok := defaultNodeAgent.remoteRoute(pb.MessageID())
+	if !ok {
 		return c.SendTypePb(packet.Data, pb)
 	}
 	pbdata, err := proto.Marshal(pb)
 	if err != nil {
 		return fmt.Errorf("[Connection/Send] Marshal %w", err)
 	}
-	return remoteCall(c, c.PackCodec, packet.NewInternal(packet.InternalData, pb.MessageID(), c.ID(), pbdata), pb.NodeName())
+	return remoteCall(c, c.PackCodec, packet.NewInternal(packet.InternalData, pb.MessageID(), c.ID(), pbdata), name)
 }
 
 func (c *Connection) SendTypePb(typ packet.Type, pb protomessage.ProtoMessage) error {
@@ -84,26 +102,72 @@ func (c *Connection) SendTypePb(typ packet.Type, pb protomessage.ProtoMessage) e
 	return c.SendPack(packet.New(typ, pb.MessageID(), pbdata))
 }
 
+func (c *Connection) Reply(seq uint32, pb protomessage.ProtoMessage) error {
+	if c.IsClosed() {
+		return errors.New("[Connection/Reply] connection closed")
+	}
+	pbdata, err := proto.Marshal(pb)
+	if err != nil {
+		return fmt.Errorf("[Connection/Reply] Marshal %w", err)
+	}
+	return c.SendPack(packet.NewWithHeader(packet.Header{Type: packet.Data, ID: pb.MessageID(), Seq: seq}, pbdata))
+}
+
+func (c *Connection) ReplyError(seq uint32, id int32, code int32, msg string) error {
+	pbdata, err := proto.Marshal(&M2CError{Code: code, Message: msg})
+	if err != nil {
+		return fmt.Errorf("[Connection/ReplyError] Marshal %w", err)
+	}
+	return c.SendPack(packet.NewWithHeader(packet.Header{Type: packet.Error, ID: id, Seq: seq}, pbdata))
+}
+
 func (c *Connection) SendData(bdata []byte) error {
 	if c.IsClosed() {
 		return errors.New("[Connection/SendData] connection closed")
 	}
+	c.push(outbound{data: bdata})
+	return nil
+}
+
+// forward queues the packet b packed by a backend for this client, packed
+// again when the client negotiated compression.
+func (c *Connection) forward(b []byte) error {
+	rb, err := c.PackCodec.Reframe(b)
+	if err != nil {
+		return fmt.Errorf("[Connection/forward] Reframe %w", err)
+	}
+	if rb == nil {
+		return c.SendData(b)
+	}
+	return c.SendBuffer(rb)
+}
+
+// SendBuffer queues the packed packets in b and frees b once written.
+func (c *Connection) SendBuffer(b *packet.Buffer) error {
+	if c.IsClosed() {
+		b.Free()
+		return errors.New("[Connection/SendBuffer] connection closed")
+	}
+	c.push(outbound{data: b.B, buf: b})
+	return nil
+}
+
+func (c *Connection) push(o outbound) {
 	c.writeCond.L.Lock()
-	c.writeQ.Push(bdata)
+	c.writeQ.Push(o)
 	c.writeCond.Signal()
 	c.writeCond.L.Unlock()
-	return nil
 }
 
 func (c *Connection) SendPack(pack *packet.Packet) error {
 	if c.IsClosed() {
 		return errors.New("[Connection/SendPack] connection closed")
 	}
-	bdata, err := c.PackCodec.Pack(pack.Type(), pack.ID(), pack.SID(), pack.Data())
+	b, err := c.PackCodec.PackBuffer(pack.Header(), pack.Data())
 	if err != nil {
 		return fmt.Errorf("[Connection/Send] Pack %w", err)
 	}
-	return c.SendData(bdata)
+	return c.SendBuffer(b)
 }
 
 func (c *Connection) Notify(s []session.Session, pb protomessage.ProtoMessage) error {
@@ -135,6 +199,8 @@ func (c *Connection) Close() error {
 	return nil
 }
 
+// writeLoop writes the queued packets into the netpoll buffer of the
+// connection and flushes each batch with a single write.
 func (c *Connection) writeLoop() {
 	defer func() { c.Close() }()
 	for {
@@ -146,14 +212,26 @@ func (c *Connection) writeLoop() {
 			c.writeCond.L.Unlock()
 			return
 		}
-		bdaba, _ := c.writeQ.PopSingleThread()
 		c.writeCond.L.Unlock()
 
-		if len(bdaba) == 0 {
+		w := c.Connection.Writer()
+		n := 0
+		for ; n < maxWriteBatch; n++ {
+			o, ok := c.writeQ.PopSingleThread()
+			if !ok {
+				break
+			}
+			err := c.WritePacket(w, o.data)
+			o.buf.Free()
+			if err != nil {
+				logx.Err.Printf("[Connection/writeLoop] WritePacket %v", err)
+				return
+			}
+		}
+		if n == 0 {
 			continue
 		}
-		_, err := c.Connection.Write(bdaba)
-		if err != nil {
+		if err := w.Flush(); err != nil {
 			logx.Err.Println(err)
 			return
 		}
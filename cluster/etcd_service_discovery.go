@@ -47,6 +47,12 @@ func (e *EtcdServiceDiscovery) RegisterService(name, advertiseAddr string, front
 		e.nodeAgent.setNode(name, vn.Id, advertiseAddr, vn.Frontend)
 		return nil
 	}
+	if err := ValidateMessages(name); errors.Is(err, ErrMessageCollision) {
+		return fmt.Errorf("[EtcdServiceDiscovery/RegisterService] %w", err)
+	} else if err != nil {
+		// a gate may front messages owned by another service
+		logx.War.Printf("[EtcdServiceDiscovery/RegisterService] %v", err)
+	}
 	grsp, err := e.client.Grant(context.Background(), e.ttl)
 	if err != nil {
 		return err
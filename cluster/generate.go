@@ -0,0 +1,3 @@
+package cluster
+
+//go:generate protoc --go_out=paths=source_relative:. --msgmeta_out=paths=source_relative,registry=false,handlers=false:. cluster.proto
@@ -0,0 +1,187 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"infra-foundation/packet"
+	"infra-foundation/protomessage"
+	"infra-foundation/session"
+	"maps"
+	"slices"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Group is a named set of sessions such as a room, guild or world channel.
+// Membership is kept on the gate each session is connected to, so a
+// broadcast costs one NotifyData packet per gate whatever the group size.
+// Sessions leave their groups when they close.
+type Group struct {
+	name string
+}
+
+func GroupOf(name string) Group {
+	return Group{name: name}
+}
+
+func (g Group) Name() string { return g.name }
+
+func (g Group) Join(s ...session.Session) error {
+	return g.update(false, s)
+}
+
+func (g Group) Leave(s ...session.Session) error {
+	return g.update(true, s)
+}
+
+// Members returns the IDs of the members connected to this gate.
+func (g Group) Members() []int64 {
+	return defaultGroups.members(g.name)
+}
+
+func (g Group) update(leave bool, ss []session.Session) error {
+	var (
+		local []int64
+		gates = map[int64][]int64{}
+	)
+	for _, s := range ss {
+		if _, ok := s.(*NetPollConnection); ok {
+			local = append(local, s.ID())
+			continue
+		}
+		agent, err := defaultNodeAgent.getGateNode(s)
+		if err != nil {
+			return fmt.Errorf("[Group/update] %s %w", g.name, err)
+		}
+		gates[agent.ID()] = append(gates[agent.ID()], s.ID())
+	}
+	defaultGroups.update(g.name, leave, local)
+	var errs []error
+	for id, sids := range gates {
+		conn, ok := defaultNodeAgent.connManager.GetByID(id)
+		if !ok {
+			errs = append(errs, fmt.Errorf("[Group/update] gate %d %w", id, ErrRemoteNotFound))
+			continue
+		}
+		errs = append(errs, sendOneWay(conn.(sender), &N2MGroup{Group: g.name, SessionID: sids, Leave: leave}))
+	}
+	return errors.Join(errs...)
+}
+
+// Notify sends pb to every member of the group on every gate.
+func (g Group) Notify(pb protomessage.ProtoMessage) error {
+	pdata, err := proto.Marshal(pb)
+	if err != nil {
+		return fmt.Errorf("[Group/Notify] proto Marshal %w", err)
+	}
+	// packed for any client, each gate reframes it with the client's codec
+	bdata, err := packet.NewPackCodec().Pack(packet.Data, pb.MessageID(), 0, pdata)
+	if err != nil {
+		return fmt.Errorf("[Group/Notify] codec Pack %w", err)
+	}
+	var errs []error
+	if defaultNodeAgent.node != nil && defaultNodeAgent.node.Frontend {
+		errs = append(errs, defaultGroups.deliver(g.name, bdata))
+	}
+	notify := &N2MNotify{Group: g.name, Plyload: bdata}
+	for _, id := range defaultNodeAgent.gateIDs() {
+		conn, err := defaultNodeAgent.nodeConn(id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, conn.SendTypePb(packet.NotifyData, notify))
+	}
+	if err = errors.Join(errs...); err != nil {
+		return fmt.Errorf("[Group/Notify] %s %w", g.name, err)
+	}
+	return nil
+}
+
+// gateIDs returns the IDs of the frontend nodes other than this one.
+func (n *NodeAgent) gateIDs() []string {
+	n.m.RLock()
+	defer n.m.RUnlock()
+	var ids []string
+	for id, nd := range n.idNodes {
+		if nd.Frontend && (n.node == nil || id != n.node.Id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func sendOneWay(conn sender, pb protomessage.ProtoMessage) error {
+	typ, payload, err := marshalMessage(pb)
+	if err != nil {
+		return err
+	}
+	return conn.SendTypePb(packet.Request, &N2MRequest{Type: typ, Payload: payload})
+}
+
+type groupRegistry struct {
+	mu        sync.RWMutex
+	groups    map[string]map[int64]struct{}
+	bySession map[int64]map[string]struct{}
+}
+
+var defaultGroups = &groupRegistry{groups: map[string]map[int64]struct{}{}, bySession: map[int64]map[string]struct{}{}}
+
+func (r *groupRegistry) update(name string, leave bool, sids []int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sid := range sids {
+		if leave {
+			r.remove(name, sid)
+			continue
+		}
+		if r.groups[name] == nil {
+			r.groups[name] = map[int64]struct{}{}
+		}
+		if r.bySession[sid] == nil {
+			r.bySession[sid] = map[string]struct{}{}
+		}
+		r.groups[name][sid] = struct{}{}
+		r.bySession[sid][name] = struct{}{}
+	}
+}
+
+func (r *groupRegistry) remove(name string, sid int64) {
+	delete(r.groups[name], sid)
+	if len(r.groups[name]) == 0 {
+		delete(r.groups, name)
+	}
+	delete(r.bySession[sid], name)
+	if len(r.bySession[sid]) == 0 {
+		delete(r.bySession, sid)
+	}
+}
+
+// leaveAll removes a closed session from its groups.
+func (r *groupRegistry) leaveAll(sid int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name := range r.bySession[sid] {
+		r.remove(name, sid)
+	}
+}
+
+func (r *groupRegistry) members(name string) []int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Sorted(maps.Keys(r.groups[name]))
+}
+
+func (r *groupRegistry) deliver(name string, bdata []byte) error {
+	connManager := defaultNodeAgent.svr.ConnManager()
+	var errs []error
+	for _, sid := range r.members(name) {
+		conn, ok := connManager.GetByID(sid)
+		if !ok {
+			continue
+		}
+		errs = append(errs, forwardClient(conn, bdata))
+	}
+	return errors.Join(errs...)
+}
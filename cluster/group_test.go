@@ -0,0 +1,116 @@
+package cluster
+
+import (
+	"bytes"
+	"infra-foundation/example/protos"
+	"infra-foundation/packet"
+	"infra-foundation/protomessage"
+	"infra-foundation/session"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/netpoll"
+	"google.golang.org/protobuf/proto"
+)
+
+type recordSession struct {
+	testGateSession
+	got [][]byte
+}
+
+func (r *recordSession) SendData(b []byte) error { r.got = append(r.got, b); return nil }
+func (r *recordSession) SendTypePb(packet.Type, protomessage.ProtoMessage) error {
+	return nil
+}
+
+func TestGroupMembership(t *testing.T) {
+	svr := NewServer()
+	a := &recordSession{testGateSession: testGateSession{session.NewNetworkEntities(201, 1)}}
+	b := &recordSession{testGateSession: testGateSession{session.NewNetworkEntities(202, 2)}}
+	svr.ConnManager().StoreSession(a)
+	svr.ConnManager().StoreSession(b)
+	defer svr.ConnManager().RemoveByID(201)
+	defer svr.ConnManager().RemoveByID(202)
+
+	// a backend joins both sessions through their gate
+	if err := sendOneWay(&loopback{}, &N2MGroup{Group: "room", SessionID: []int64{201, 202}}); err != nil {
+		t.Fatal(err)
+	}
+	if m := GroupOf("room").Members(); !slices.Equal(m, []int64{201, 202}) {
+		t.Fatalf("members = %v", m)
+	}
+	if err := defaultGroups.deliver("room", []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.got) != 1 || len(b.got) != 1 {
+		t.Fatalf("delivered %d %d", len(a.got), len(b.got))
+	}
+
+	if err := sendOneWay(&loopback{}, &N2MGroup{Group: "room", SessionID: []int64{202}, Leave: true}); err != nil {
+		t.Fatal(err)
+	}
+	defaultGroups.update("world", false, []int64{201})
+	defaultGroups.leaveAll(201)
+	if len(defaultGroups.groups) != 0 || len(defaultGroups.bySession) != 0 {
+		t.Fatalf("groups left behind %v %v", defaultGroups.groups, defaultGroups.bySession)
+	}
+}
+
+// wireConn is a connection writing into an in-memory buffer.
+type wireConn struct {
+	closeRecorder
+	w *netpoll.LinkBuffer
+}
+
+func (c *wireConn) Writer() netpoll.Writer { return c.w }
+
+func TestGroupNotifyCompressed(t *testing.T) {
+	agent := defaultNodeAgent
+	defaultNodeAgent = newNodeAgent()
+	t.Cleanup(func() { defaultNodeAgent = agent })
+	defaultNodeAgent.node = &node{Id: "1", Name: "GATE", Frontend: true}
+	svr := NewServer()
+
+	wire := &wireConn{w: netpoll.NewLinkBuffer(1024)}
+	c := NewConnection(wire, 301, 1)
+	defer c.Close()
+	client := packet.NewPackCodec()
+	client.EnableCompression(64)
+	offer, _ := client.Handshake()
+	ps, _ := c.PackCodec.Unpack(offer)
+	var answer []byte
+	if err := c.PackCodec.Accept(ps[0], func(b []byte) error { answer = b; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	ps, _ = client.Unpack(answer)
+	if err := client.Accept(ps[0], nil); err != nil || client.Compression() == packet.CompressionNone {
+		t.Fatalf("compression not negotiated %v", err)
+	}
+	svr.ConnManager().StoreSession(c)
+	defer svr.ConnManager().RemoveByID(301)
+	defaultGroups.update("hall", false, []int64{301})
+	defer defaultGroups.leaveAll(301)
+
+	login := &protos.C2SLogin{Name: strings.Repeat("snapshot ", 400)}
+	if err := GroupOf("hall").Notify(login); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for wire.w.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	out, _ := wire.w.Next(wire.w.Len())
+	if len(out) <= packet.HeadLength || packet.Flags(out[packet.HeadLength])&packet.FlagCompressed == 0 {
+		t.Fatalf("notify of %d bytes not compressed", len(out))
+	}
+	ps, err := client.Unpack(out)
+	if err != nil || len(ps) != 1 {
+		t.Fatalf("packets = %v %v", ps, err)
+	}
+	want, _ := proto.Marshal(login)
+	if ps[0].ID() != login.MessageID() || !bytes.Equal(ps[0].Data(), want) {
+		t.Fatalf("notify = %v", ps[0])
+	}
+}
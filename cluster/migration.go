@@ -0,0 +1,225 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infra-foundation/logx"
+	"infra-foundation/packet"
+	"infra-foundation/protomessage"
+	"infra-foundation/session"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+var ErrSessionMigrating = errors.New("cluster: session is migrating")
+
+// maxHeldPackets bounds the client packets a gate holds for a migrating
+// session. A client sending more is disconnected.
+const maxHeldPackets = 1 << 12
+
+// MigrateSession moves the session sid, connected to this gate and bound to
+// a node of service, to the node target of the same service. Client data for
+// the session is held at the gate while the models of the source node export
+// its state and the target node imports it, then flushed in order to target.
+func MigrateSession(ctx context.Context, sid int64, service, target string) error {
+	conn, ok := defaultNodeAgent.svr.ConnManager().GetByID(sid)
+	if !ok {
+		return fmt.Errorf("[MigrateSession] SessionID: %d not found", sid)
+	}
+	sconn, ok := conn.(*NetPollConnection)
+	if !ok {
+		return fmt.Errorf("[MigrateSession] SessionID: %d is not connected to this gate", sid)
+	}
+	source := sconn.GetServers(service)
+	if source == "" {
+		return fmt.Errorf("[MigrateSession] SessionID: %d not bound to %s", sid, service)
+	}
+	if source == target {
+		return nil
+	}
+	src, err := defaultNodeAgent.nodeConn(source)
+	if err != nil {
+		return fmt.Errorf("[MigrateSession] source %w", err)
+	}
+	dst, err := defaultNodeAgent.nodeConn(target)
+	if err != nil {
+		return fmt.Errorf("[MigrateSession] target %w", err)
+	}
+
+	if err = sconn.pauseRouting(ctx); err != nil {
+		return fmt.Errorf("[MigrateSession] SessionID: %d %w", sid, err)
+	}
+	defer sconn.resumeRouting()
+
+	state, err := callSession(ctx, src, &N2MSessionExport{SessionID: sid})
+	if err != nil {
+		return fmt.Errorf("[MigrateSession] SessionID: %d export from %s %w", sid, source, err)
+	}
+	servers := maps.Clone(sconn.Servers())
+	servers[service] = target
+	bind := &N2MOnSessionBindServer{SessionID: sid, UID: sconn.UID(), Servers: servers, Attrs: sconn.Attrs().Replicated()}
+	if _, err = callSession(ctx, dst, &N2MSessionImport{Bind: bind, Models: state.GetModels()}); err != nil {
+		restore := &N2MSessionImport{Bind: &N2MOnSessionBindServer{SessionID: sid, UID: sconn.UID(), Servers: sconn.Servers(), Attrs: sconn.Attrs().Replicated()}, Models: state.GetModels()}
+		if _, rerr := callSession(context.WithoutCancel(ctx), src, restore); rerr != nil {
+			err = errors.Join(err, fmt.Errorf("restore on %s %w", source, rerr))
+		}
+		return fmt.Errorf("[MigrateSession] SessionID: %d import on %s %w", sid, target, err)
+	}
+
+	sconn.BindServers(service, target)
+	sconn.modelManager.OnBindServer(sconn, servers)
+	var errs []error
+	for name, id := range servers {
+		if name == defaultNodeAgent.node.Name || id == target {
+			continue
+		}
+		if c, err := defaultNodeAgent.nodeConn(id); err == nil {
+			errs = append(errs, c.SendTypePb(packet.BindConnection, bind))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func callSession(ctx context.Context, conn sender, pb protomessage.ProtoMessage) (*N2MSessionState, error) {
+	typ, payload, err := marshalMessage(pb)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := defaultRPC.call(ctx, conn, &N2MRequest{Type: typ, Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	state, _ := resp.(*N2MSessionState)
+	return state, nil
+}
+
+func (n *NodeAgent) nodeConn(id string) (sender, error) {
+	iid, _ := strconv.Atoi(id)
+	conn, ok := n.connManager.GetByID(int64(iid))
+	if !ok {
+		return nil, fmt.Errorf("NodeId: %s %w", id, ErrRemoteNotFound)
+	}
+	return conn.(sender), nil
+}
+
+// exportSession hands the state of a session over to the node migrating it
+// and forgets the session without delivering close events.
+func exportSession(ctx context.Context, pb *N2MSessionExport, reply func(protomessage.ProtoMessage, error)) {
+	connManager := defaultNodeAgent.svr.ConnManager()
+	conn, ok := connManager.GetByID(pb.SessionID)
+	if !ok {
+		reply(nil, fmt.Errorf("[exportSession] SessionID: %d not found", pb.SessionID))
+		return
+	}
+	go func() {
+		states, err := defaultNodeAgent.svr.ModelManager().ExportSession(ctx, conn)
+		if err != nil {
+			reply(nil, err)
+			return
+		}
+		connManager.RemoveByID(pb.SessionID)
+		if a, ok := conn.(*acceptor); ok {
+			a.closed.Store(true)
+		}
+		reply(&N2MSessionState{SessionID: pb.SessionID, Models: states}, nil)
+	}()
+}
+
+// importSession creates the acceptor of a migrated session and restores its
+// state before any message for it is routed here.
+func importSession(ctx context.Context, pb *N2MSessionImport, reply func(protomessage.ProtoMessage, error)) {
+	bind := pb.GetBind()
+	connManager := defaultNodeAgent.svr.ConnManager()
+	if _, ok := connManager.GetByID(bind.GetSessionID()); ok {
+		reply(nil, fmt.Errorf("[importSession] SessionID: %d already exists", bind.GetSessionID()))
+		return
+	}
+	a := newAcceptor(session.NewNetworkEntities(bind.GetSessionID(), -1), defaultNodeAgent.svr)
+	a.NetworkEntities.BindUID(bind.GetUID())
+	a.Attrs().Apply(bind.GetAttrs())
+	for name, id := range bind.GetServers() {
+		a.BindServers(name, id)
+	}
+	go func() {
+		if err := a.modelManager.ImportSession(ctx, a, pb.GetModels()); err != nil {
+			reply(nil, err)
+			return
+		}
+		connManager.StoreSession(a)
+		a.modelManager.OnBindServer(a, a.Servers())
+		reply(nil, nil)
+	}()
+}
+
+// pauseRouting makes the gate hold the client data of the connection meant
+// for other nodes until resumeRouting. Both run on the work queue of the
+// connection, so held packets keep their order relative to later ones. When
+// ctx ends first, a pause taking effect later is undone.
+func (n *NetPollConnection) pauseRouting(ctx context.Context) error {
+	var paused bool
+	done := make(chan error, 1)
+	if err := n.workMessage.Put(n.ID(), func() {
+		if n.held != nil {
+			done <- ErrSessionMigrating
+			return
+		}
+		n.held = []*packet.Packet{}
+		paused = true
+		done <- nil
+	}); err != nil {
+		return err
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	if err := n.workMessage.Put(n.ID(), func() {
+		if paused {
+			n.flushHeld()
+		}
+	}); err != nil {
+		logx.Err.Printf("[NetPollConnection/pauseRouting] SessionID: %d %v", n.ID(), err)
+	}
+	return ctx.Err()
+}
+
+func (n *NetPollConnection) resumeRouting() {
+	if err := n.workMessage.Put(n.ID(), n.flushHeld); err != nil {
+		logx.Err.Printf("[NetPollConnection/resumeRouting] SessionID: %d %v", n.ID(), err)
+	}
+}
+
+// flushHeld routes the held packets and ends the pause. It runs on the work
+// queue of the connection.
+func (n *NetPollConnection) flushHeld() {
+	held := n.held
+	n.held = nil
+	for _, pk := range held {
+		if err := remoteCall(n, n.PackCodec, pk, defaultNodeAgent.getGroutes(pk.ID())); err != nil {
+			logx.Err.Printf("[NetPollConnection/resumeRouting] SessionID: %d MessageID: %d %v", n.ID(), pk.ID(), err)
+		}
+		pk.Free()
+	}
+}
+
+// hold keeps a packet for later routing while the session is migrating. A
+// client overflowing the hold is closed and its held packets dropped, so
+// none is routed out of order.
+func (n *NetPollConnection) hold(id int32, sid int64, seq uint32, bdata []byte) (bool, error) {
+	if n.held == nil {
+		return false, nil
+	}
+	if len(n.held) >= maxHeldPackets {
+		for _, pk := range n.held {
+			pk.Free()
+		}
+		n.held = n.held[:0]
+		n.Close()
+		return true, fmt.Errorf("[NetPollConnection/hold] SessionID: %d %d packets held while migrating", n.ID(), maxHeldPackets)
+	}
+	n.held = append(n.held, packet.NewWithHeader(packet.Header{Type: packet.InternalData, ID: id, SID: sid, Seq: seq}, slices.Clone(bdata)))
+	return true, nil
+}
@@ -0,0 +1,255 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"infra-foundation/model"
+	"infra-foundation/packet"
+	"infra-foundation/protomessage"
+	"infra-foundation/session"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type migrateModel struct{ scores map[int64]int }
+
+func (m *migrateModel) Name() string                    { return "migrate" }
+func (m *migrateModel) OnInit() error                   { return nil }
+func (m *migrateModel) OnStart() error                  { return nil }
+func (m *migrateModel) OnStop() error                   { return nil }
+func (m *migrateModel) OnDisconnection(session.Session) {}
+
+func (m *migrateModel) ExportSession(s session.Session) ([]byte, error) {
+	score := m.scores[s.ID()]
+	delete(m.scores, s.ID())
+	return []byte(strconv.Itoa(score)), nil
+}
+
+func (m *migrateModel) ImportSession(s session.Session, state []byte) error {
+	score, err := strconv.Atoi(string(state))
+	m.scores[s.ID()] = score
+	return err
+}
+
+func TestSessionExportImport(t *testing.T) {
+	svr := NewServer()
+	mm := &migrateModel{scores: map[int64]int{}}
+	if err := model.Register(mm); err != nil {
+		t.Fatal(err)
+	}
+	defer model.DefaultModelManager.Unregister("migrate")
+	md, _ := model.DefaultModelManager.GetModel("migrate")
+
+	bindAcceptor(svr.ConnManager(), &N2MOnSessionBindServer{SessionID: 77, UID: 5, Servers: map[string]string{"GAME": "1"}})
+	for range 3 {
+		md.PostFunc(func() { mm.scores[77]++ })
+	}
+
+	ctx := context.Background()
+	state, err := callSession(ctx, &loopback{}, &N2MSessionExport{SessionID: 77})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(state.GetModels()["migrate"]); got != "3" {
+		t.Fatalf("exported %q", got)
+	}
+	if _, ok := svr.ConnManager().GetByID(77); ok {
+		t.Fatal("session kept after export")
+	}
+
+	bind := &N2MOnSessionBindServer{SessionID: 77, UID: 5, Servers: map[string]string{"GAME": "2"}}
+	if _, err = callSession(ctx, &loopback{}, &N2MSessionImport{Bind: bind, Models: state.GetModels()}); err != nil {
+		t.Fatal(err)
+	}
+	conn, ok := svr.ConnManager().GetByID(77)
+	if !ok || conn.UID() != 5 || conn.GetServers("GAME") != "2" {
+		t.Fatalf("imported session %v", conn)
+	}
+	score, _ := model.Do(ctx, md, func() (int, error) { return mm.scores[77], nil })
+	if score != 3 {
+		t.Fatalf("imported score %d", score)
+	}
+	if _, err = callSession(ctx, &loopback{}, &N2MSessionImport{Bind: bind}); err == nil {
+		t.Fatal("imported a session twice")
+	}
+}
+
+// nodeLink is a backend node seen from the gate. It records the client data
+// routed to it and answers session imports; exports are answered by the test.
+type nodeLink struct {
+	*session.NetworkEntities
+	mu      sync.Mutex
+	data    []string
+	exports chan *N2MRequest
+}
+
+func newNodeLink() *nodeLink {
+	return &nodeLink{NetworkEntities: session.NewNetworkEntities(0, -1), exports: make(chan *N2MRequest, 1)}
+}
+
+func (l *nodeLink) Send(protomessage.ProtoMessage) error                      { return nil }
+func (l *nodeLink) Notify([]session.Session, protomessage.ProtoMessage) error { return nil }
+func (l *nodeLink) Close() error                                              { return nil }
+
+func (l *nodeLink) SendData(b []byte) error {
+	pks, err := packet.NewPackCodec().Unpack(b)
+	l.mu.Lock()
+	for _, pk := range pks {
+		l.data = append(l.data, string(pk.Data()))
+	}
+	l.mu.Unlock()
+	return err
+}
+
+func (l *nodeLink) SendTypePb(typ packet.Type, pb protomessage.ProtoMessage) error {
+	switch typ {
+	case packet.Response:
+		return defaultRPC.resolve(pb.(*N2MResponse))
+	case packet.Request:
+		req := pb.(*N2MRequest)
+		msg, err := unmarshalMessage(req.Type, req.Payload)
+		if err != nil || req.Cancel {
+			return err
+		}
+		if _, ok := msg.(*N2MSessionExport); ok {
+			l.exports <- req
+			return nil
+		}
+		return replyRequest(l, req, nil, nil)
+	}
+	return nil
+}
+
+func (l *nodeLink) received() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.data)
+}
+
+// migrationGate makes the test a gate routing message 9001 to the GAME nodes
+// 1 and 2, and returns a client session bound to node 1.
+func migrationGate(t *testing.T) (*ServerRequest, *NetPollConnection, *nodeLink, *nodeLink) {
+	agent := defaultNodeAgent
+	defaultNodeAgent = newNodeAgent()
+	t.Cleanup(func() { defaultNodeAgent = agent })
+	defaultNodeAgent.setNode("GATE", "9", "", true)
+	defaultNodeAgent.nodes["GAME"] = []*node{{Id: "1", Name: "GAME", Routes: []int32{9001}}, {Id: "2", Name: "GAME", Routes: []int32{9001}}}
+	defaultNodeAgent.rebuildRoutes()
+	src, dst := newNodeLink(), newNodeLink()
+	defaultNodeAgent.storeNodeConn("1", src)
+	defaultNodeAgent.storeNodeConn("2", dst)
+
+	req := NewServer().(*server).svrrequest
+	conn := req.OnPrepare(&closeRecorder{}).Value(ctxKeyConnection).(*NetPollConnection)
+	t.Cleanup(func() { session.DefaultConnSession.Remove(conn.ID()) })
+	conn.BindServers("GAME", "1")
+	return req, conn, src, dst
+}
+
+// route hands client data to the gate as its reader does.
+func route(t *testing.T, req *ServerRequest, conn *NetPollConnection, data string) {
+	if err := req.workMessage.Put(conn.ID(), func() {
+		if err := req.onMessage(conn, packet.Data, 9001, conn.ID(), 0, []byte(data)); err != nil {
+			t.Error(err)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// drainQueue waits for the tasks queued for conn so far.
+func drainQueue(req *ServerRequest, conn *NetPollConnection) {
+	done := make(chan struct{})
+	req.workMessage.Put(conn.ID(), func() { close(done) })
+	<-done
+}
+
+func TestMigrateSessionHoldsData(t *testing.T) {
+	req, conn, src, dst := migrationGate(t)
+	route(t, req, conn, "a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	migrated := make(chan error, 1)
+	go func() { migrated <- MigrateSession(ctx, conn.ID(), "GAME", "2") }()
+
+	var export *N2MRequest
+	select {
+	case export = <-src.exports:
+	case <-ctx.Done():
+		t.Fatal("export not requested")
+	}
+	// routing is paused while the source exports
+	route(t, req, conn, "b")
+	route(t, req, conn, "c")
+	drainQueue(req, conn)
+	if got := src.received(); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("source received %v", got)
+	}
+	if got := dst.received(); len(got) != 0 {
+		t.Fatalf("target received %v before import", got)
+	}
+
+	if err := replyRequest(src, export, &N2MSessionState{SessionID: conn.ID()}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-migrated; err != nil {
+		t.Fatal(err)
+	}
+	route(t, req, conn, "d")
+	drainQueue(req, conn)
+	if got := dst.received(); !slices.Equal(got, []string{"b", "c", "d"}) {
+		t.Fatalf("target received %v", got)
+	}
+	if got := src.received(); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("source received %v", got)
+	}
+	if conn.GetServers("GAME") != "2" {
+		t.Fatalf("bound to %q", conn.GetServers("GAME"))
+	}
+}
+
+func TestPauseRoutingHonorsContext(t *testing.T) {
+	req, conn, src, _ := migrationGate(t)
+	unblock := make(chan struct{})
+	req.workMessage.Put(conn.ID(), func() { <-unblock })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := conn.pauseRouting(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+	close(unblock)
+	route(t, req, conn, "a")
+	drainQueue(req, conn)
+	if got := src.received(); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("routing left paused, source received %v", got)
+	}
+}
+
+func TestHoldOverflowClosesSession(t *testing.T) {
+	req, conn, src, _ := migrationGate(t)
+	if err := conn.pauseRouting(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	done := make(chan struct{})
+	req.workMessage.Put(conn.ID(), func() {
+		defer close(done)
+		for i := 0; i <= maxHeldPackets && err == nil; i++ {
+			_, err = conn.hold(9001, conn.ID(), 0, []byte("x"))
+		}
+	})
+	<-done
+	if err == nil || !conn.IsClosed() {
+		t.Fatalf("err = %v closed = %v", err, conn.IsClosed())
+	}
+	conn.resumeRouting()
+	drainQueue(req, conn)
+	if got := src.received(); len(got) != 0 {
+		t.Fatalf("dropped packets routed: %d", len(got))
+	}
+}
@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"infra-foundation/logx"
+	"infra-foundation/packet"
 	"infra-foundation/scheduler"
 	"infra-foundation/session"
 	"sync/atomic"
@@ -18,6 +19,8 @@ type NetPollConnection struct {
 	heartbeatInterval time.Duration
 	timerID           scheduler.TimerID
 	closed            atomic.Bool
+	connected         atomic.Bool
+	held              []*packet.Packet
 }
 
 func NewNetPollConnection(svrrequest *ServerRequest, connection netpoll.Connection, id int64) *NetPollConnection {
@@ -27,13 +30,14 @@ func NewNetPollConnection(svrrequest *ServerRequest, connection netpoll.Connecti
 		heartbeatInterval: time.Second * 5,
 	}
 
+	n.Connection.SetClock(svrrequest.scheduler.Clock())
 	n.ServerRequest.connManager.StoreSession(n)
 	n.timerID, _ = n.scheduler.PushEvery(n.heartbeatInterval, n.checkHeartbeat)
 	return n
 }
 
 func (n *NetPollConnection) checkHeartbeat() {
-	now := time.Now().Unix()
+	now := n.scheduler.Clock().Now().Unix()
 	if n.HeartbeatAt() == 0 {
 		n.SetHeartbeatAt(now)
 		return
@@ -45,6 +49,34 @@ func (n *NetPollConnection) checkHeartbeat() {
 	logx.Dbg.Println("[NetPollConnection/checkHeartbeat] 心跳超时 ", n.ID())
 }
 
+// accepted delivers OnConnection for a connection accepted by a frontend node.
+// Until it identifies itself as a node link every such connection is a client
+// session, as it is for the ConnManager.
+func (n *NetPollConnection) accepted() {
+	if self := defaultNodeAgent.node; self != nil && !self.Frontend {
+		return
+	}
+	n.connected.Store(true)
+	n.modelManager.OnConnection(n)
+}
+
+// markNode withdraws the client session of a connection that identified
+// itself as a node link.
+func (n *NetPollConnection) markNode() {
+	if n.connected.CompareAndSwap(true, false) {
+		n.modelManager.OnSessionClose(n)
+	}
+}
+
+func (n *NetPollConnection) BindUID(uid int64) {
+	old := n.UID()
+	n.Connection.BindUID(uid)
+	n.connManager.RebindUID(n, old)
+	if uid > 0 && uid != old && n.connected.Load() {
+		n.modelManager.OnBindUID(n, uid)
+	}
+}
+
 func (n *NetPollConnection) Close() error {
 	if !n.closed.CompareAndSwap(false, true) {
 		return nil
@@ -54,6 +86,10 @@ func (n *NetPollConnection) Close() error {
 	} else {
 		n.modelManager.OnDisconnection(n)
 		n.ServerRequest.connManager.RemoveByID(n.ID())
+		defaultGroups.leaveAll(n.ID())
+	}
+	if n.connected.CompareAndSwap(true, false) {
+		n.modelManager.OnSessionClose(n)
 	}
 	n.scheduler.CancelTimer(n.timerID)
 	return n.Connection.Close()
@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"context"
+	"infra-foundation/connmannger"
+	"infra-foundation/model"
+	"infra-foundation/scheduler"
+	"infra-foundation/session"
+	"slices"
+	"sync"
+	"testing"
+)
+
+type connEventModel struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (m *connEventModel) Name() string                    { return "connEvents" }
+func (m *connEventModel) OnInit() error                   { return nil }
+func (m *connEventModel) OnStart() error                  { return nil }
+func (m *connEventModel) OnStop() error                   { return nil }
+func (m *connEventModel) Shards() int                     { return 4 }
+func (m *connEventModel) OnDisconnection(session.Session) { m.add("disconnection") }
+func (m *connEventModel) OnConnection(session.Session)    { m.add("connection") }
+func (m *connEventModel) OnBindUID(session.Session, int64) {
+	m.add("uid")
+}
+func (m *connEventModel) OnSessionClose(session.Session) { m.add("close") }
+
+func (m *connEventModel) add(ev string) {
+	m.mu.Lock()
+	m.events = append(m.events, ev)
+	m.mu.Unlock()
+}
+
+func (m *connEventModel) take() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	events := m.events
+	m.events = nil
+	return events
+}
+
+func TestNetPollConnectionEvents(t *testing.T) {
+	mm := model.NewModelManager()
+	em := &connEventModel{}
+	mm.Register(em)
+	if err := mm.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer mm.Stop()
+	sched := scheduler.NewScheduler()
+	defer sched.Stop()
+	req := &ServerRequest{connManager: connmannger.NewConnManager(), modelManager: mm, scheduler: sched}
+	md, _ := mm.GetModel("connEvents")
+	drain := func(s session.Session) {
+		model.DoKey(context.Background(), md, uint64(s.ID()), func() (struct{}, error) { return struct{}{}, nil })
+	}
+
+	// A client connection is announced when accepted, before it sends data.
+	ctx := req.OnPrepare(&closeRecorder{})
+	client := ctx.Value(ctxKeyConnection).(*NetPollConnection)
+	defer session.DefaultConnSession.Remove(client.ID())
+	drain(client)
+	if got := em.take(); !slices.Equal(got, []string{"connection"}) {
+		t.Fatalf("events after accept = %v", got)
+	}
+	client.BindUID(7)
+	client.Close()
+	drain(client)
+	if got, want := em.take(), []string{"uid", "disconnection", "close"}; !slices.Equal(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+
+	// A node link is withdrawn once it identifies itself.
+	ctx = req.OnPrepare(&closeRecorder{})
+	link := ctx.Value(ctxKeyConnection).(*NetPollConnection)
+	defer session.DefaultConnSession.Remove(link.ID())
+	link.markNode()
+	link.Close()
+	drain(link)
+	if got, want := em.take(), []string{"connection", "close"}; !slices.Equal(got, want) {
+		t.Fatalf("node link events = %v, want %v", got, want)
+	}
+}
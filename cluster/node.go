@@ -6,22 +6,31 @@ import (
 	"fmt"
 	"infra-foundation/connmannger"
 	"infra-foundation/logx"
+	"infra-foundation/model"
 	"infra-foundation/packet"
 	"infra-foundation/protomessage"
 	"infra-foundation/session"
 	"maps"
 	"math/rand"
+	"slices"
 	"strconv"
 	"sync"
 	"sync/atomic"
+
+	"google.golang.org/protobuf/proto"
 )
 
 type node struct {
-	Id       string
-	Name     string
-	Addr     string
-	Frontend bool
-	Routes   []int32
+	Id           string
+	Name         string
+	Addr         string
+	Frontend     bool
+	Routes       []int32
+	Actors       []string          `json:",omitempty"`
+	Models       []string          `json:",omitempty"`
+	RouteVersion int64             `json:",omitempty"`
+	Version      string            `json:",omitempty"`
+	Labels       map[string]string `json:",omitempty"`
 }
 
 func (n *node) connection(id, name string) error {
@@ -35,15 +44,17 @@ func (n *node) connection(id, name string) error {
 }
 
 type NodeAgent struct {
-	svr         Server
-	node        *node
-	nodes       map[string][]*node
-	idNodes     map[string]*node
-	m           sync.RWMutex
-	tryState    atomic.Bool
-	groutes     map[int32]string
-	groutesrw   sync.RWMutex
-	connManager *connmannger.ConnManager
+	svr          Server
+	node         *node
+	nodes        map[string][]*node
+	idNodes      map[string]*node
+	m            sync.RWMutex
+	tryState     atomic.Bool
+	routes       atomic.Pointer[routeTable]
+	routeVersion atomic.Int64
+	version      string
+	labels       map[string]string
+	connManager  *connmannger.ConnManager
 }
 
 type sender interface {
@@ -51,6 +62,26 @@ type sender interface {
 	SendData(data []byte) error
 }
 
+// sendBuffer sends the pooled buffer b through s. Connections free b once
+// written; other senders get its bytes and b is left to the collector.
+func sendBuffer(s session.Session, b *packet.Buffer) error {
+	if bs, ok := s.(interface{ SendBuffer(*packet.Buffer) error }); ok {
+		return bs.SendBuffer(b)
+	}
+	return s.(sender).SendData(b.B)
+}
+
+// marshalBuffer marshals pb into a pooled buffer.
+func marshalBuffer(pb protomessage.ProtoMessage) (*packet.Buffer, error) {
+	b := packet.GetBuffer(proto.Size(pb))
+	var err error
+	if b.B, err = (proto.MarshalOptions{}).MarshalAppend(b.B[:0], pb); err != nil {
+		b.Free()
+		return nil, err
+	}
+	return b, nil
+}
+
 var (
 	_                = (*NodeAgent).removeByNameOrAddr
 	_                = (*NodeAgent).getGroutes
@@ -65,7 +96,6 @@ func newNodeAgent() *NodeAgent {
 	return &NodeAgent{
 		nodes:       map[string][]*node{},
 		idNodes:     map[string]*node{},
-		groutes:     map[int32]string{},
 		connManager: connmannger.NewConnManager(),
 	}
 }
@@ -108,6 +138,15 @@ func (n *NodeAgent) notifyCloseSession(s session.Session) error {
 	return errors.Join(errs...)
 }
 
+// forwardClient sends the packet b packed by a backend to the client s,
+// letting the client connection reframe it with its own codec.
+func forwardClient(s session.Session, b []byte) error {
+	if f, ok := s.(interface{ forward([]byte) error }); ok {
+		return f.forward(b)
+	}
+	return s.(sender).SendData(b)
+}
+
 func (n *NodeAgent) getGateNode(s session.Session) (session.Session, error) {
 	for _, v := range s.Servers() {
 		node, ok := n.idNodes[v]
@@ -137,7 +176,8 @@ func (n *NodeAgent) pick(name string, s session.Session) (session.Session, error
 	if len(nodes) == 0 {
 		return nil, fmt.Errorf("%s len == 0", name)
 	}
-	node := nodes[rand.Int()%len(n.nodes[name])]
+	nodes = candidates(name, s.UID(), nodes)
+	node := nodes[rand.Int()%len(nodes)]
 	id, _ := strconv.Atoi(node.Id)
 	conn, ok := n.connManager.GetByID(int64(id))
 	if !ok {
@@ -145,16 +185,44 @@ func (n *NodeAgent) pick(name string, s session.Session) (session.Session, error
 	}
 	s.BindServers(name, node.Id)
 	s.BindServers(defaultNodeAgent.node.Name, defaultNodeAgent.node.Id)
-	pb := &N2MOnSessionBindServer{SessionID: s.ID(), UID: s.UID(), Servers: s.Servers()}
+	pb := &N2MOnSessionBindServer{SessionID: s.ID(), UID: s.UID(), Servers: s.Servers(), Attrs: s.Attrs().Replicated()}
+	if n.svr != nil {
+		n.svr.ModelManager().OnBindServer(s, pb.Servers)
+	}
 	return conn, conn.(sender).SendTypePb(packet.BindConnection, pb)
 }
 
+// pickModel returns the connection to a remote node hosting the model name.
+func (n *NodeAgent) pickModel(name string) (sender, error) {
+	n.m.RLock()
+	var hosts []*node
+	for id, node := range n.idNodes {
+		if n.node != nil && id == n.node.Id {
+			continue
+		}
+		if slices.Contains(node.Models, name) {
+			hosts = append(hosts, node)
+		}
+	}
+	n.m.RUnlock()
+	for len(hosts) > 0 {
+		i := rand.Intn(len(hosts))
+		id, _ := strconv.Atoi(hosts[i].Id)
+		if conn, ok := n.connManager.GetByID(int64(id)); ok {
+			return conn.(sender), nil
+		}
+		hosts = slices.Delete(hosts, i, i+1)
+	}
+	return nil, fmt.Errorf("[NodeAgent/pickModel] %s %w", name, ErrRemoteNotFound)
+}
+
 func (n *NodeAgent) addNode(name, id, addr string, frontend bool, rids []int32) {
 	n.m.Lock()
-	node := &node{Id: id, Name: name, Addr: addr, Frontend: frontend, Routes: rids}
+	node := &node{Id: id, Name: name, Addr: addr, Frontend: frontend, Routes: rids, Actors: defaultActors.localKinds(), Models: model.DefaultModelManager.Names(), RouteVersion: n.routeVersion.Load(), Version: n.version, Labels: n.labels}
 	n.nodes[name] = append(n.nodes[name], node)
 	n.idNodes[id] = node
 	n.m.Unlock()
+	n.rebuildRoutes()
 }
 
 func (n *NodeAgent) storeNodeConn(id string, conn session.Session) {
@@ -172,8 +240,8 @@ func (n *NodeAgent) Marshal(name, id, addr string, frontend bool, rids []int32)
 
 func (n *NodeAgent) removeByNameOrId(name, id string) {
 	n.m.Lock()
-	defer n.m.Unlock()
 	if _, ok := n.nodes[name]; !ok {
+		n.m.Unlock()
 		return
 	}
 	var newNodes []*node
@@ -188,6 +256,9 @@ func (n *NodeAgent) removeByNameOrId(name, id string) {
 		delete(n.nodes, name)
 	}
 	delete(n.idNodes, id)
+	n.m.Unlock()
+	n.rebuildRoutes()
+	defaultActors.rebuild(n.mapList())
 }
 
 func (n *NodeAgent) removeByNameOrAddr(name, addr string) {
@@ -237,11 +308,6 @@ func (n *NodeAgent) Unmarshal(k string, sb []byte) error {
 	var mns = make(map[string]*node, len(vns))
 	for _, vv := range vns {
 		mns[vv.Id] = vv
-		n.groutesrw.Lock()
-		for _, id := range vv.Routes {
-			n.groutes[id] = vv.Name
-		}
-		n.groutesrw.Unlock()
 		if _, ok := v[vv.Id]; ok {
 			continue
 		}
@@ -256,22 +322,35 @@ func (n *NodeAgent) Unmarshal(k string, sb []byte) error {
 		}
 	}
 	n.m.Lock()
+	for _, vv := range n.nodes[k] {
+		if _, ok := mns[vv.Id]; !ok && vv.Id != n.node.Id {
+			delete(n.idNodes, vv.Id)
+		}
+	}
 	n.nodes[k] = vns
 	maps.Copy(n.idNodes, mns)
 	logx.Dbg.Println(k, string(sb), n.idNodes)
 	n.m.Unlock()
+	n.rebuildRoutes()
+	defaultActors.rebuild(n.mapList())
 	return nil
 }
 
 func (n *NodeAgent) getGroutes(id int32) string {
-	n.groutesrw.RLock()
-	defer n.groutesrw.RUnlock()
-	return n.groutes[id]
+	return n.routeTable().selected[id]
 }
 
 func (n *NodeAgent) hasGroutes(id int32) bool {
-	n.groutesrw.RLock()
-	defer n.groutesrw.RUnlock()
-	_, ok := n.groutes[id]
+	_, ok := n.routeTable().selected[id]
 	return ok
 }
+
+// remoteRoute returns the service the route table selected for id when that
+// is not the local one.
+func (n *NodeAgent) remoteRoute(id int32) (string, bool) {
+	name, ok := n.routeTable().selected[id]
+	if !ok || (n.node != nil && n.node.Name == name) {
+		return "", false
+	}
+	return name, true
+}
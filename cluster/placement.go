@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"hash/fnv"
+	"infra-foundation/model"
+	"slices"
+	"strconv"
+)
+
+const ringReplicas = 128
+
+type hashRing struct {
+	points []uint64
+	owners map[uint64]string
+}
+
+func newHashRing(ids []string) *hashRing {
+	r := &hashRing{owners: make(map[uint64]string, len(ids)*ringReplicas)}
+	for _, id := range ids {
+		for i := range ringReplicas {
+			h := hashString(id + "#" + strconv.Itoa(i))
+			if _, ok := r.owners[h]; ok {
+				continue
+			}
+			r.owners[h] = id
+			r.points = append(r.points, h)
+		}
+	}
+	slices.Sort(r.points)
+	return r
+}
+
+func (r *hashRing) owner(key string) (string, bool) {
+	if r == nil || len(r.points) == 0 {
+		return "", false
+	}
+	h := hashString(key)
+	i, _ := slices.BinarySearch(r.points, h)
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]], true
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return model.Mix64(h.Sum64())
+}
@@ -0,0 +1,199 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"infra-foundation/logx"
+	"infra-foundation/model"
+	"infra-foundation/protomessage"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeliveryMode selects the guarantees of a published message. Every mode
+// delivers a message at most once to each subscription; Ordered also runs
+// the handlers of a publisher's messages in publish order. An Ordered
+// message arriving after a later one from the same publisher is dropped,
+// not delivered late.
+type DeliveryMode int
+
+const (
+	AtMostOnce DeliveryMode = iota
+	Ordered
+)
+
+// Subscription is a topic subscription of a model.
+type Subscription struct {
+	bus     *pubsub
+	model   string
+	pattern []string
+	handle  func(topic string, msg protomessage.ProtoMessage)
+}
+
+func (s *Subscription) Unsubscribe() {
+	s.bus.unsubscribe(s)
+}
+
+type pubsub struct {
+	node  func() string
+	peers func() []sender
+	mm    *model.ModelManager
+	epoch int64
+
+	mu   sync.Mutex // serializes sequence numbers with their sends
+	seq  uint64
+	subs []*Subscription
+	subu sync.RWMutex
+	last map[string]publisherSeq
+	lmu  sync.Mutex
+}
+
+type publisherSeq struct {
+	epoch int64
+	seq   uint64
+}
+
+var defaultPubSub = newPubSub(model.DefaultModelManager, func() string {
+	if defaultNodeAgent.node == nil {
+		return ""
+	}
+	return defaultNodeAgent.node.Id
+}, func() []sender {
+	var peers []sender
+	for s := range defaultNodeAgent.connManager.All() {
+		peers = append(peers, s.(sender))
+	}
+	return peers
+})
+
+func newPubSub(mm *model.ModelManager, node func() string, peers func() []sender) *pubsub {
+	return &pubsub{node: node, peers: peers, mm: mm, epoch: time.Now().UnixNano(), last: map[string]publisherSeq{}}
+}
+
+// Subscribe runs fn in the mailbox of the model named modelName for every
+// message of type T published on a topic matching pattern. Topics are dot
+// separated; in a pattern "*" matches one segment and a trailing ">" matches
+// one or more segments.
+func Subscribe[T protomessage.ProtoMessage](modelName, pattern string, fn func(topic string, msg T)) (*Subscription, error) {
+	return subscribe(defaultPubSub, modelName, pattern, fn)
+}
+
+func subscribe[T protomessage.ProtoMessage](bus *pubsub, modelName, pattern string, fn func(topic string, msg T)) (*Subscription, error) {
+	if _, ok := bus.mm.GetModel(modelName); !ok {
+		return nil, fmt.Errorf("[Subscribe] %s %w", modelName, model.ErrModelNotFound)
+	}
+	segs := strings.Split(pattern, ".")
+	for i, seg := range segs {
+		if seg == "" || (seg == ">" && i != len(segs)-1) {
+			return nil, fmt.Errorf("[Subscribe] invalid pattern %q", pattern)
+		}
+	}
+	sub := &Subscription{bus: bus, model: modelName, pattern: segs, handle: func(topic string, msg protomessage.ProtoMessage) {
+		if m, ok := msg.(T); ok {
+			fn(topic, m)
+		}
+	}}
+	bus.subu.Lock()
+	bus.subs = append(bus.subs, sub)
+	bus.subu.Unlock()
+	return sub, nil
+}
+
+func (p *pubsub) unsubscribe(sub *Subscription) {
+	p.subu.Lock()
+	p.subs = slices.DeleteFunc(p.subs, func(s *Subscription) bool { return s == sub })
+	p.subu.Unlock()
+}
+
+// Publish sends pb to the subscribers of topic on every node, this one
+// included.
+func Publish(topic string, pb protomessage.ProtoMessage, mode DeliveryMode) error {
+	return defaultPubSub.publish(topic, pb, mode)
+}
+
+func (p *pubsub) publish(topic string, pb protomessage.ProtoMessage, mode DeliveryMode) error {
+	if topic == "" || strings.ContainsAny(topic, "*>") {
+		return fmt.Errorf("[Publish] invalid topic %q", topic)
+	}
+	typ, payload, err := marshalMessage(pb)
+	if err != nil {
+		return fmt.Errorf("[Publish] %s %w", topic, err)
+	}
+	msg := &N2MPublish{Topic: topic, Publisher: p.node(), Epoch: p.epoch, Ordered: mode == Ordered, Type: typ, Payload: payload}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seq++
+	msg.Seq = p.seq
+	p.dispatch(msg, pb)
+	var errs []error
+	for _, peer := range p.peers() {
+		errs = append(errs, sendOneWay(peer, msg))
+	}
+	if err = errors.Join(errs...); err != nil {
+		return fmt.Errorf("[Publish] %s %w", topic, err)
+	}
+	return nil
+}
+
+// receive delivers a message published by another node.
+func (p *pubsub) receive(msg *N2MPublish) {
+	if msg.Ordered && !p.advance(msg) {
+		return
+	}
+	pb, err := unmarshalMessage(msg.Type, msg.Payload)
+	if err != nil {
+		logx.Err.Printf("[pubsub/receive] %s %v", msg.Topic, err)
+		return
+	}
+	p.dispatch(msg, pb)
+}
+
+// advance records the sequence of an ordered message and reports whether it
+// is newer than every message already delivered from its publisher.
+func (p *pubsub) advance(msg *N2MPublish) bool {
+	p.lmu.Lock()
+	defer p.lmu.Unlock()
+	last, ok := p.last[msg.Publisher]
+	if ok && last.epoch == msg.Epoch && msg.Seq <= last.seq {
+		return false
+	}
+	if ok && last.epoch > msg.Epoch {
+		return false
+	}
+	p.last[msg.Publisher] = publisherSeq{epoch: msg.Epoch, seq: msg.Seq}
+	return true
+}
+
+func (p *pubsub) dispatch(msg *N2MPublish, pb protomessage.ProtoMessage) {
+	topic := strings.Split(msg.Topic, ".")
+	p.subu.RLock()
+	defer p.subu.RUnlock()
+	for _, sub := range p.subs {
+		if !matchTopic(sub.pattern, topic) {
+			continue
+		}
+		md, ok := p.mm.GetModel(sub.model)
+		if !ok {
+			continue
+		}
+		key := msg.Seq
+		if msg.Ordered {
+			key = hashString(msg.Publisher)
+		}
+		md.PostFuncKey(key, func() { sub.handle(msg.Topic, pb) })
+	}
+}
+
+func matchTopic(pattern, topic []string) bool {
+	for i, seg := range pattern {
+		if seg == ">" {
+			return len(topic) > i
+		}
+		if i >= len(topic) || (seg != "*" && seg != topic[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(topic)
+}
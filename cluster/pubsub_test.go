@@ -0,0 +1,235 @@
+package cluster
+
+import (
+	"context"
+	"infra-foundation/model"
+	"infra-foundation/packet"
+	"infra-foundation/protomessage"
+	"infra-foundation/session"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/netpoll"
+	"google.golang.org/protobuf/proto"
+)
+
+type subModel struct{ got []string }
+
+func (m *subModel) Name() string                    { return "sub" }
+func (m *subModel) OnInit() error                   { return nil }
+func (m *subModel) OnStart() error                  { return nil }
+func (m *subModel) OnStop() error                   { return nil }
+func (m *subModel) OnDisconnection(session.Session) {}
+
+// busLink carries publish requests between the nodes of an in-process
+// cluster, encoded as they would be on the wire.
+type busLink struct{ to *pubsub }
+
+func (l busLink) SendData([]byte) error { return nil }
+
+func (l busLink) SendTypePb(typ packet.Type, pb protomessage.ProtoMessage) error {
+	b, err := proto.Marshal(pb)
+	if err != nil {
+		return err
+	}
+	var req N2MRequest
+	if err = proto.Unmarshal(b, &req); err != nil {
+		return err
+	}
+	msg, err := unmarshalMessage(req.Type, req.Payload)
+	if err != nil {
+		return err
+	}
+	l.to.receive(msg.(*N2MPublish))
+	return nil
+}
+
+type testNode struct {
+	bus *pubsub
+	mm  *model.ModelManager
+	sub *subModel
+}
+
+func newTestCluster(t *testing.T, ids ...string) map[string]*testNode {
+	nodes := map[string]*testNode{}
+	for _, id := range ids {
+		n := &testNode{mm: model.NewModelManager(), sub: &subModel{}}
+		if err := n.mm.Register(n.sub); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { n.mm.Stop() })
+		n.bus = newPubSub(n.mm, func() string { return id }, func() []sender {
+			var peers []sender
+			for other, on := range nodes {
+				if other != id {
+					peers = append(peers, busLink{on.bus})
+				}
+			}
+			return peers
+		})
+		nodes[id] = n
+	}
+	return nodes
+}
+
+func (n *testNode) received(t *testing.T) []string {
+	md, _ := n.mm.GetModel("sub")
+	got, err := model.Do(context.Background(), md, func() ([]string, error) { return slices.Clone(n.sub.got), nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestPubSubCluster(t *testing.T) {
+	nodes := newTestCluster(t, "a", "b", "c")
+	record := func(n *testNode, pattern string) {
+		_, err := subscribe(n.bus, "sub", pattern, func(topic string, msg *N2MOnSessionClose) {
+			n.sub.got = append(n.sub.got, topic)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	record(nodes["a"], "guild.>")
+	record(nodes["b"], "guild.*.disbanded")
+	record(nodes["c"], "config.>")
+
+	a := nodes["a"].bus
+	if err := a.publish("guild.7.disbanded", &N2MOnSessionClose{SessionID: 7}, AtMostOnce); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.publish("config.reload", &N2MOnSessionClose{}, Ordered); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.publish("guild.7.member.left", &N2MOnSessionClose{}, AtMostOnce); err != nil {
+		t.Fatal(err)
+	}
+	if got := nodes["a"].received(t); !slices.Equal(got, []string{"guild.7.disbanded", "guild.7.member.left"}) {
+		t.Fatalf("a got %v", got)
+	}
+	if got := nodes["b"].received(t); !slices.Equal(got, []string{"guild.7.disbanded"}) {
+		t.Fatalf("b got %v", got)
+	}
+	if got := nodes["c"].received(t); !slices.Equal(got, []string{"config.reload"}) {
+		t.Fatalf("c got %v", got)
+	}
+
+	// ordered delivery per publisher, stale messages dropped
+	var want []int64
+	var seen []int64
+	if _, err := subscribe(nodes["c"].bus, "sub", "seq.*", func(_ string, msg *N2MOnSessionClose) {
+		seen = append(seen, msg.SessionID)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range int64(50) {
+		want = append(want, i)
+		if err := nodes["b"].bus.publish("seq.x", &N2MOnSessionClose{SessionID: i}, Ordered); err != nil {
+			t.Fatal(err)
+		}
+	}
+	typ, payload, _ := marshalMessage(&N2MOnSessionClose{SessionID: -1})
+	nodes["c"].bus.receive(&N2MPublish{Topic: "seq.x", Publisher: "b", Epoch: nodes["b"].bus.epoch, Seq: 1, Ordered: true, Type: typ, Payload: payload})
+	nodes["c"].received(t)
+	if !slices.Equal(seen, want) {
+		t.Fatalf("ordered = %v", seen)
+	}
+}
+
+// TestPubSubOverNodeLink carries published messages over a node link dialed
+// over TCP. The test process holds both ends: the accepted end is the only
+// peer of the default bus, and the dialing end serves the requests it
+// receives with the rpc and hands them back to the default bus.
+func TestPubSubOverNodeLink(t *testing.T) {
+	agent := defaultNodeAgent
+	defaultNodeAgent = newNodeAgent()
+	t.Cleanup(func() { defaultNodeAgent = agent })
+	defaultNodeAgent.setNode("GAME", "1", "", false)
+
+	svr := NewServer().(*server)
+	ln, err := netpoll.CreateListener("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	poll, err := netpoll.NewEventLoop(svr.svrrequest.OnRequest,
+		netpoll.WithOnPrepare(svr.svrrequest.OnPrepare),
+		netpoll.WithOnDisconnect(svr.svrrequest.OnDisconnect))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go poll.Serve(ln)
+	t.Cleanup(func() { poll.Shutdown(context.Background()) })
+
+	if err = (&node{Id: "1", Name: "GAME", Addr: ln.Addr().String()}).connection("2", "GAME"); err != nil {
+		t.Fatal(err)
+	}
+	var dialer session.Session
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		_, accepted := defaultNodeAgent.connManager.GetByID(2)
+		dialer, _ = defaultNodeAgent.connManager.GetByID(1)
+		if accepted && dialer != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("node link not identified")
+		}
+	}
+	// node 2 keeps its end of the link in its own agent
+	defaultNodeAgent.connManager.RemoveByID(1)
+	t.Cleanup(func() { dialer.Close() })
+
+	sub := &subModel{}
+	if err = model.Register(sub); err != nil {
+		t.Fatal(err)
+	}
+	defer model.DefaultModelManager.Unregister("sub")
+	got := make(chan int64, 100)
+	s1, err := Subscribe("sub", "seq.*", func(_ string, msg *N2MOnSessionClose) { got <- msg.SessionID })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s1.Unsubscribe()
+
+	// a second bus publishes through the peers of the default one, so the
+	// messages reach the subscription only over the wire, in its order
+	node2 := newPubSub(model.NewModelManager(), func() string { return "2" }, defaultPubSub.peers)
+	if peers := defaultPubSub.peers(); len(peers) != 1 {
+		t.Fatalf("peers = %d", len(peers))
+	}
+	for i := range int64(100) {
+		if err = node2.publish("seq.x", &N2MOnSessionClose{SessionID: i}, Ordered); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range int64(100) {
+		select {
+		case id := <-got:
+			if id != i {
+				t.Fatalf("received %d, want %d", id, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %d not delivered", i)
+		}
+	}
+}
+
+func TestMatchTopic(t *testing.T) {
+	for _, c := range []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"a.b", "a.b", true},
+		{"a.*", "a.b", true},
+		{"a.*", "a.b.c", false},
+		{"a.>", "a.b.c", true},
+		{"a.>", "a", false},
+		{"*.b", "a.c", false},
+	} {
+		if got := matchTopic(strings.Split(c.pattern, "."), strings.Split(c.topic, ".")); got != c.want {
+			t.Errorf("matchTopic(%q, %q) = %v", c.pattern, c.topic, got)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package cluster
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"infra-foundation/logx"
+	"infra-foundation/model"
+	"infra-foundation/protomessage"
+	"io"
+	"slices"
+	"sync"
+)
+
+var (
+	ErrMessageCollision = errors.New("cluster: message ID collision")
+	ErrMessageOwnership = errors.New("cluster: message ownership conflict")
+)
+
+// MessageInfo is one entry of the message catalog. Service and Model come
+// from the generated message metadata, Owners are the services whose nodes
+// advertise a route for the ID and Owner the one selected by the route policy.
+type MessageInfo struct {
+	ID        int32        `json:"id"`
+	Name      string       `json:"name"`
+	Service   string       `json:"service,omitempty"`
+	Model     string       `json:"model,omitempty"`
+	Owner     string       `json:"owner,omitempty"`
+	Owners    []RouteOwner `json:"owners,omitempty"`
+	Local     bool         `json:"local,omitempty"`
+	Conflicts []string     `json:"conflicts,omitempty"`
+}
+
+type messageConflicts struct {
+	mu   sync.Mutex
+	byID map[int32][]string
+}
+
+var conflicts = &messageConflicts{byID: map[int32][]string{}}
+
+// reset replaces the recorded conflicts with those found by the latest route
+// rebuild, so that a conflict resolved by a node leaving or upgrading is
+// dropped. Conflicts not seen before are logged.
+func (c *messageConflicts) reset(found map[int32][]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, errs := range found {
+		for _, e := range errs {
+			if !slices.Contains(c.byID[id], e) {
+				logx.War.Println(e)
+			}
+		}
+	}
+	c.byID = found
+}
+
+func (c *messageConflicts) get(id int32) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.byID[id])
+}
+
+// ValidateMessages checks the handlers of the local node, registered as
+// service, against the generated message registry: a handler whose message
+// type differs from the registered type for its ID is a collision, and a
+// handler for a message owned by another service is an ownership conflict.
+// RegisterService refuses collisions and only logs ownership conflicts, as a
+// gate may handle messages owned by the services behind it.
+func ValidateMessages(service string) error {
+	var errs []error
+	for _, h := range model.LocalHandlers() {
+		pb, ok := protomessage.Lookup(h.ID)
+		if !ok {
+			continue
+		}
+		if name := string(pb.ProtoReflect().Descriptor().FullName()); name != h.Message {
+			errs = append(errs, fmt.Errorf("[ValidateMessages] ID %d handler %s, registered %s %w", h.ID, h.Message, name, ErrMessageCollision))
+		}
+		if owner := pb.NodeName(); owner != "" && owner != service {
+			errs = append(errs, fmt.Errorf("[ValidateMessages] ID %d %s owned by %s, handled by %s %w", h.ID, h.Message, owner, service, ErrMessageOwnership))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Catalog returns every message known to this node ordered by ID.
+func Catalog() []MessageInfo {
+	infos := map[int32]*MessageInfo{}
+	get := func(id int32) *MessageInfo {
+		if info, ok := infos[id]; ok {
+			return info
+		}
+		info := &MessageInfo{ID: id}
+		infos[id] = info
+		return info
+	}
+	for _, pb := range protomessage.Registered() {
+		info := get(pb.MessageID())
+		info.Name, info.Service, info.Model = string(pb.ProtoReflect().Descriptor().FullName()), pb.NodeName(), pb.ModeName()
+	}
+	for _, h := range model.LocalHandlers() {
+		info := get(h.ID)
+		info.Local = true
+		if info.Name == "" {
+			info.Name, info.Model = h.Message, h.Model
+		}
+	}
+	routes := defaultNodeAgent.routeTable()
+	for id, owners := range routes.owners {
+		info := get(id)
+		info.Owner, info.Owners = routes.selected[id], slices.Clone(owners)
+	}
+
+	catalog := make([]MessageInfo, 0, len(infos))
+	for _, info := range infos {
+		info.Conflicts = conflicts.get(info.ID)
+		catalog = append(catalog, *info)
+	}
+	slices.SortFunc(catalog, func(a, b MessageInfo) int { return cmp.Compare(a.ID, b.ID) })
+	return catalog
+}
+
+// UnroutedMessages returns the registered messages of a service that neither
+// this node nor any known node handles.
+func UnroutedMessages() []MessageInfo {
+	var unrouted []MessageInfo
+	for _, info := range Catalog() {
+		if info.Service != "" && !info.Local && info.Owner == "" {
+			unrouted = append(unrouted, info)
+		}
+	}
+	return unrouted
+}
+
+// DumpCatalog writes the message catalog to w as JSON.
+func DumpCatalog(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Catalog())
+}
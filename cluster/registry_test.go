@@ -0,0 +1,131 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"infra-foundation/example/protos"
+	"infra-foundation/model"
+	"infra-foundation/protomessage"
+	"infra-foundation/session"
+	"testing"
+)
+
+func TestRouteTableVersions(t *testing.T) {
+	n := newNodeAgent()
+	n.nodes["GAME"] = []*node{{Id: "1", Name: "GAME", Routes: []int32{9001, 9002}, RouteVersion: 1}, {Id: "2", Name: "GAME", Routes: []int32{9001, 9002}, RouteVersion: 1}}
+	n.rebuildRoutes()
+	if n.getGroutes(9001) != "GAME" {
+		t.Fatalf("9001 -> %q", n.getGroutes(9001))
+	}
+
+	// battle takes over 9001 during a rolling deploy
+	n.nodes["BATTLE"] = []*node{{Id: "3", Name: "BATTLE", Routes: []int32{9001}, RouteVersion: 2}}
+	n.rebuildRoutes()
+	if n.getGroutes(9001) != "BATTLE" || n.getGroutes(9002) != "GAME" {
+		t.Fatalf("9001 -> %q, 9002 -> %q", n.getGroutes(9001), n.getGroutes(9002))
+	}
+	if owners := n.routeTable().owners[9001]; len(owners) != 2 || owners[1] != (RouteOwner{Service: "GAME", Version: 1, Nodes: 2}) {
+		t.Fatalf("owners = %v", owners)
+	}
+	n.node = &node{Id: "1", Name: "GAME"}
+	if name, ok := n.remoteRoute(9001); !ok || name != "BATTLE" {
+		t.Fatalf("remote route 9001 = %q %v", name, ok)
+	}
+	if name, ok := n.remoteRoute(9002); ok {
+		t.Fatalf("local route 9002 sent to %q", name)
+	}
+
+	n.nodes["BATTLE"] = nil
+	n.rebuildRoutes()
+	if n.getGroutes(9001) != "GAME" {
+		t.Fatalf("9001 -> %q after battle left", n.getGroutes(9001))
+	}
+	delete(n.nodes, "GAME")
+	n.rebuildRoutes()
+	if n.hasGroutes(9001) || n.hasGroutes(9002) {
+		t.Fatal("routes kept after every owner left")
+	}
+}
+
+func TestRouteConflictsCleared(t *testing.T) {
+	n := newNodeAgent()
+	t.Cleanup(defaultNodeAgent.rebuildRoutes)
+	n.nodes["GAME"] = []*node{{Id: "1", Name: "GAME", Routes: []int32{9001}, RouteVersion: 1}}
+	n.nodes["BATTLE"] = []*node{{Id: "2", Name: "BATTLE", Routes: []int32{9001}, RouteVersion: 1}}
+	n.rebuildRoutes()
+	if c := conflicts.get(9001); len(c) != 1 {
+		t.Fatalf("conflicts = %v", c)
+	}
+
+	// battle upgrades, so the tie on 9001 is gone
+	n.nodes["BATTLE"][0].RouteVersion = 2
+	n.rebuildRoutes()
+	if c := conflicts.get(9001); len(c) != 0 {
+		t.Fatalf("conflicts after upgrade = %v", c)
+	}
+}
+
+func TestUnmarshalDropsDepartedNodes(t *testing.T) {
+	n := newNodeAgent()
+	n.node = &node{Id: "9", Name: "GATE"}
+	t.Cleanup(func() { defaultActors.rebuild(defaultNodeAgent.mapList()) })
+	both, _ := json.Marshal([]*node{{Id: "1", Name: "GAME", Models: []string{"room"}}, {Id: "2", Name: "GAME"}})
+	if err := n.Unmarshal("GAME", both); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := n.idNodes["1"]; !ok {
+		t.Fatal("node 1 not indexed")
+	}
+
+	// node 1 leaves the service
+	left, _ := json.Marshal([]*node{{Id: "2", Name: "GAME"}})
+	if err := n.Unmarshal("GAME", left); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := n.idNodes["1"]; ok {
+		t.Fatal("departed node 1 still indexed")
+	}
+	if _, ok := n.idNodes["2"]; !ok {
+		t.Fatal("node 2 dropped")
+	}
+	if _, err := n.pickModel("room"); !errors.Is(err, ErrRemoteNotFound) {
+		t.Fatalf("pickModel after leave = %v", err)
+	}
+}
+
+func TestValidateMessagesAndCatalog(t *testing.T) {
+	login := &protos.C2SLogin{}
+	model.RegisterHandler(login, func(session.Session, protomessage.ProtoMessage) {})
+	defer model.Handlers.Delete(login.MessageID())
+
+	if err := ValidateMessages("GAME"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateMessages("GATE"); !errors.Is(err, ErrMessageOwnership) {
+		t.Fatalf("err = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := DumpCatalog(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var catalog []MessageInfo
+	if err := json.Unmarshal(buf.Bytes(), &catalog); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, info := range catalog {
+		if info.ID == login.MessageID() {
+			found = info.Name == "user.C2SLogin" && info.Service == "GAME" && info.Model == "user" && info.Local
+		}
+	}
+	if !found {
+		t.Fatalf("catalog = %s", buf.String())
+	}
+	for _, info := range UnroutedMessages() {
+		if info.ID == login.MessageID() {
+			t.Fatalf("locally handled message reported unrouted")
+		}
+	}
+}
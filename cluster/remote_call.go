@@ -6,27 +6,25 @@ import (
 )
 
 func remoteCall(s session.Session, p *packet.PackCodec, pack *packet.Packet, nodeName string) error {
-	var (
-		agent session.Session
-		err   error
-	)
-	switch {
-	case defaultNodeAgent.hasGroutes(pack.ID()):
-		agent, err = defaultNodeAgent.getNodeByName(s, nodeName)
-		if err != nil {
-			return err
-		}
-	case defaultNodeAgent.node.Frontend:
-		agent = s
-	default:
-		agent, err = defaultNodeAgent.getGateNode(s)
-		if err != nil {
-			return err
-		}
+	agent, err := remoteAgent(s, pack.ID(), nodeName)
+	if err != nil {
+		return err
 	}
-	bdata, err := p.Pack(pack.Type(), pack.ID(), pack.SID(), pack.Data())
+	b, err := p.PackBuffer(pack.Header(), pack.Data())
 	if err != nil {
 		return err
 	}
-	return agent.(sender).SendData(bdata)
+	return sendBuffer(agent, b)
+}
+
+// remoteAgent returns the connection a packet with message id leaves s by.
+func remoteAgent(s session.Session, id int32, nodeName string) (session.Session, error) {
+	switch {
+	case defaultNodeAgent.hasGroutes(id):
+		return defaultNodeAgent.getNodeByName(s, nodeName)
+	case defaultNodeAgent.node.Frontend:
+		return s, nil
+	default:
+		return defaultNodeAgent.getGateNode(s)
+	}
 }
@@ -0,0 +1,107 @@
+package cluster
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync/atomic"
+)
+
+// RouteOwner is one service announcing a route for a message ID. Version is
+// the highest route version announced by the nodes of the service.
+type RouteOwner struct {
+	Service string `json:"service"`
+	Version int64  `json:"version"`
+	Nodes   int    `json:"nodes"`
+}
+
+// RoutePolicy selects the service a message is routed to among its owners,
+// ordered by descending version then service name. An empty result leaves
+// the ID unrouted.
+type RoutePolicy func(id int32, owners []RouteOwner) string
+
+// NewestRoute routes to the owner with the highest announced version, so
+// that a rolling deploy moves a message to its new service as soon as one
+// node of it is up, while the old service keeps serving until then.
+func NewestRoute(_ int32, owners []RouteOwner) string {
+	if len(owners) == 0 {
+		return ""
+	}
+	return owners[0].Service
+}
+
+var routePolicy atomic.Pointer[RoutePolicy]
+
+// SetRoutePolicy replaces the policy used for IDs owned by several services.
+func SetRoutePolicy(p RoutePolicy) {
+	routePolicy.Store(&p)
+	defaultNodeAgent.rebuildRoutes()
+}
+
+func currentRoutePolicy() RoutePolicy {
+	if p := routePolicy.Load(); p != nil {
+		return *p
+	}
+	return NewestRoute
+}
+
+// SetRouteVersion sets the version announced with the routes of the local
+// node. It must be called before RegisterService.
+func SetRouteVersion(v int64) {
+	defaultNodeAgent.routeVersion.Store(v)
+}
+
+type routeTable struct {
+	owners   map[int32][]RouteOwner
+	selected map[int32]string
+}
+
+func buildRouteTable(nodes map[string][]*node, policy RoutePolicy) *routeTable {
+	t := &routeTable{owners: map[int32][]RouteOwner{}, selected: map[int32]string{}}
+	found := map[int32][]string{}
+	for name, ns := range nodes {
+		for _, nd := range ns {
+			for _, id := range nd.Routes {
+				i := slices.IndexFunc(t.owners[id], func(o RouteOwner) bool { return o.Service == name })
+				if i < 0 {
+					t.owners[id] = append(t.owners[id], RouteOwner{Service: name, Version: nd.RouteVersion, Nodes: 1})
+					continue
+				}
+				o := &t.owners[id][i]
+				o.Version = max(o.Version, nd.RouteVersion)
+				o.Nodes++
+			}
+		}
+	}
+	for id, owners := range t.owners {
+		slices.SortFunc(owners, func(a, b RouteOwner) int {
+			return cmp.Or(cmp.Compare(b.Version, a.Version), cmp.Compare(a.Service, b.Service))
+		})
+		if len(owners) > 1 && owners[0].Version == owners[1].Version {
+			found[id] = append(found[id], fmt.Errorf("[NodeAgent/rebuildRoutes] ID %d announced by %s and %s at version %d %w", id, owners[0].Service, owners[1].Service, owners[0].Version, ErrMessageOwnership).Error())
+		}
+		if s := policy(id, slices.Clone(owners)); s != "" {
+			t.selected[id] = s
+		}
+	}
+	conflicts.reset(found)
+	return t
+}
+
+// rebuildRoutes recomputes the route table from the live node set.
+func (n *NodeAgent) rebuildRoutes() {
+	n.routes.Store(buildRouteTable(n.mapList(), currentRoutePolicy()))
+}
+
+func (n *NodeAgent) routeTable() *routeTable {
+	if t := n.routes.Load(); t != nil {
+		return t
+	}
+	return &routeTable{}
+}
+
+// RouteOwners returns the services announcing a route for id, ordered by
+// descending version.
+func RouteOwners(id int32) []RouteOwner {
+	return slices.Clone(defaultNodeAgent.routeTable().owners[id])
+}
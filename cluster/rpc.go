@@ -0,0 +1,202 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infra-foundation/logx"
+	"infra-foundation/model"
+	"infra-foundation/packet"
+	"infra-foundation/protomessage"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+var ErrRemoteNotFound = errors.New("cluster: remote node not found")
+
+type rpcPending struct {
+	seq      atomic.Uint64
+	calls    sync.Map
+	inflight sync.Map
+}
+
+type inflightKey struct {
+	conn sender
+	seq  uint64
+}
+
+var defaultRPC = &rpcPending{}
+
+func (r *rpcPending) register() (uint64, chan *N2MResponse) {
+	seq := r.seq.Add(1)
+	ch := make(chan *N2MResponse, 1)
+	r.calls.Store(seq, ch)
+	return seq, ch
+}
+
+func (r *rpcPending) remove(seq uint64) {
+	r.calls.Delete(seq)
+}
+
+func (r *rpcPending) resolve(resp *N2MResponse) error {
+	v, ok := r.calls.LoadAndDelete(resp.Seq)
+	if !ok {
+		return fmt.Errorf("[rpc/resolve] Seq %d not pending", resp.Seq)
+	}
+	v.(chan *N2MResponse) <- resp
+	return nil
+}
+
+// call sends req over conn and waits for the matching N2MResponse. The
+// context deadline travels with the request, and cancelling ctx sends a
+// cancel request so the remote side can give up as well.
+func (r *rpcPending) call(ctx context.Context, conn sender, req *N2MRequest) (protomessage.ProtoMessage, error) {
+	seq, ch := r.register()
+	defer r.remove(seq)
+	req.Seq = seq
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Deadline = deadline.UnixNano()
+	}
+	if err := conn.SendTypePb(packet.Request, req); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != "" {
+			return nil, errors.New(resp.Error)
+		}
+		if resp.Type == "" {
+			return nil, nil
+		}
+		return unmarshalMessage(resp.Type, resp.Payload)
+	case <-ctx.Done():
+		_ = conn.SendTypePb(packet.Request, &N2MRequest{Seq: seq, Cancel: true})
+		return nil, ctx.Err()
+	}
+}
+
+// serve handles an N2MRequest received from conn, dispatching it to the named
+// model or to the actor it addresses. Session migration, group membership
+// and publish requests are handled by the cluster itself.
+func (r *rpcPending) serve(conn sender, req *N2MRequest) error {
+	key := inflightKey{conn, req.Seq}
+	if req.Cancel {
+		if v, ok := r.inflight.LoadAndDelete(key); ok {
+			v.(context.CancelFunc)()
+		}
+		return nil
+	}
+	msg, err := unmarshalMessage(req.Type, req.Payload)
+	if err != nil {
+		return replyRequest(conn, req, nil, err)
+	}
+	ctx, cancel := requestContext(req)
+	if req.Seq != 0 {
+		r.inflight.Store(key, cancel)
+	}
+	done := func() {
+		r.inflight.Delete(key)
+		cancel()
+	}
+	reply := func(resp protomessage.ProtoMessage, err error) {
+		done()
+		if req.Seq == 0 && err != nil {
+			logx.Err.Printf("[rpc/serve] Model[%s] Actor[%s/%s] %v", req.Model, req.Kind, req.Key, err)
+		}
+		if err := replyRequest(conn, req, resp, err); err != nil {
+			logx.Err.Printf("[rpc/serve] reply Seq[%d] %v", req.Seq, err)
+		}
+	}
+	switch m := msg.(type) {
+	case *N2MSessionExport:
+		exportSession(ctx, m, reply)
+	case *N2MSessionImport:
+		importSession(ctx, m, reply)
+	case *N2MGroup:
+		defaultGroups.update(m.Group, m.Leave, m.SessionID)
+		reply(nil, nil)
+	case *N2MPublish:
+		defaultPubSub.receive(m)
+		reply(nil, nil)
+	default:
+		if req.Model != "" {
+			err = model.DefaultModelManager.AskAsync(ctx, req.Model, msg, reply)
+		} else {
+			err = defaultActors.deliver(ctx, ActorRef{Kind: req.Kind, Key: req.Key}, msg, reply)
+		}
+	}
+	if err != nil {
+		done()
+		return replyRequest(conn, req, nil, err)
+	}
+	return nil
+}
+
+type modelRemote struct{}
+
+func init() {
+	model.SetRemote(modelRemote{})
+}
+
+func (modelRemote) Ask(ctx context.Context, name string, req protomessage.ProtoMessage) (protomessage.ProtoMessage, error) {
+	conn, err := defaultNodeAgent.pickModel(name)
+	if err != nil {
+		return nil, err
+	}
+	typ, payload, err := marshalMessage(req)
+	if err != nil {
+		return nil, err
+	}
+	return defaultRPC.call(ctx, conn, &N2MRequest{Model: name, Type: typ, Payload: payload})
+}
+
+func requestContext(req *N2MRequest) (context.Context, context.CancelFunc) {
+	if req.Deadline == 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithDeadline(context.Background(), time.Unix(0, req.Deadline))
+}
+
+func replyRequest(conn sender, req *N2MRequest, resp protomessage.ProtoMessage, err error) error {
+	if req.Seq == 0 {
+		return nil
+	}
+	pb := &N2MResponse{Seq: req.Seq}
+	if err != nil {
+		pb.Error = err.Error()
+	} else if resp != nil {
+		pb.Type, pb.Payload, err = marshalMessage(resp)
+		if err != nil {
+			pb.Error = err.Error()
+		}
+	}
+	return conn.SendTypePb(packet.Response, pb)
+}
+
+func marshalMessage(pb protomessage.ProtoMessage) (string, []byte, error) {
+	b, err := proto.Marshal(pb)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(pb.ProtoReflect().Descriptor().FullName()), b, nil
+}
+
+func unmarshalMessage(typ string, b []byte) (protomessage.ProtoMessage, error) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(typ))
+	if err != nil {
+		return nil, fmt.Errorf("[rpc/unmarshalMessage] %s %w", typ, err)
+	}
+	pb, ok := mt.New().Interface().(protomessage.ProtoMessage)
+	if !ok {
+		return nil, fmt.Errorf("[rpc/unmarshalMessage] %s is not a ProtoMessage", typ)
+	}
+	if err = proto.Unmarshal(b, pb); err != nil {
+		return nil, fmt.Errorf("[rpc/unmarshalMessage] %s %w", typ, err)
+	}
+	return pb, nil
+}
@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"infra-foundation/example/protos"
+	"infra-foundation/model"
+	"infra-foundation/session"
+	"testing"
+	"time"
+)
+
+type rpcModel struct{}
+
+func (rpcModel) Name() string                    { return "rpc" }
+func (rpcModel) OnInit() error                   { return nil }
+func (rpcModel) OnStart() error                  { return nil }
+func (rpcModel) OnStop() error                   { return nil }
+func (rpcModel) OnDisconnection(session.Session) {}
+
+func TestRPCModelAskCancel(t *testing.T) {
+	if err := model.Register(rpcModel{}); err != nil {
+		t.Fatal(err)
+	}
+	defer model.DefaultModelManager.Unregister("rpc")
+	cancelled := make(chan error, 1)
+	model.RegisterAsk("rpc", func(ctx context.Context, req *protos.C2SLogin) (*protos.S2CLogin, error) {
+		if req.Name == "block" {
+			<-ctx.Done()
+			cancelled <- ctx.Err()
+			return nil, ctx.Err()
+		}
+		return &protos.S2CLogin{Name: req.Name}, nil
+	})
+
+	ask := func(ctx context.Context, name string) (*protos.S2CLogin, error) {
+		typ, payload, _ := marshalMessage(&protos.C2SLogin{Name: name})
+		resp, err := defaultRPC.call(ctx, &loopback{}, &N2MRequest{Model: "rpc", Type: typ, Payload: payload})
+		if err != nil {
+			return nil, err
+		}
+		return resp.(*protos.S2CLogin), nil
+	}
+	resp, err := ask(context.Background(), "hi")
+	if err != nil || resp.Name != "hi" {
+		t.Fatalf("resp = %v err = %v", resp, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err = ask(ctx, "block"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v", err)
+	}
+	select {
+	case err := <-cancelled:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("remote err = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cancel did not reach the remote handler")
+	}
+}
@@ -37,7 +37,9 @@ func NewServerRequest(svr Server) *ServerRequest {
 
 func (s *ServerRequest) OnPrepare(connection netpoll.Connection) context.Context {
 	sid := session.DefaultConnSession.SessionID()
-	return context.WithValue(context.TODO(), ctxKeyConnection, NewNetPollConnection(s, connection, sid))
+	conn := NewNetPollConnection(s, connection, sid)
+	conn.accepted()
+	return context.WithValue(context.TODO(), ctxKeyConnection, conn)
 }
 
 func (s *ServerRequest) OnDisconnect(ctx context.Context, connection netpoll.Connection) {
@@ -58,6 +60,9 @@ func (s *ServerRequest) OnRequest(ctx context.Context, connection netpoll.Connec
 	r2, err := sconn.PackCodec.NextPacket(connection.Reader())
 	if err != nil {
 		logx.Err.Printf("[ServerRequest/OnRequest] NextPacket error %v", err)
+		if _, ok := errors.AsType[*packet.ProtocolError](err); ok {
+			sconn.violate(err, true)
+		}
 		return fmt.Errorf("[ServerRequest/OnRequest] Peek error %v", err)
 	}
 	if r2 == nil {
@@ -67,9 +72,16 @@ func (s *ServerRequest) OnRequest(ctx context.Context, connection netpoll.Connec
 		pk, err := sconn.PackCodec.Unpack1(r2)
 		if err != nil {
 			logx.Err.Printf("[ServerRequest/OnRequest] Unpack error %v", err)
+			sconn.violate(err, false)
+			return
+		}
+		if err = sconn.PackCodec.Accept(pk, sconn.SendData); err != nil {
+			logx.Err.Printf("[ServerRequest/OnRequest] Accept error %v", err)
+			sconn.violate(err, true)
+			pk.Free()
 			return
 		}
-		if err = s.onMessage(sconn, pk.Type(), pk.ID(), pk.SID(), pk.Data()); err != nil {
+		if err = s.onMessage(sconn, pk.Type(), pk.ID(), pk.SID(), pk.Seq(), pk.Data()); err != nil {
 			logx.Err.Println(err)
 		}
 		pk.Free()
@@ -79,21 +91,26 @@ func (s *ServerRequest) OnRequest(ctx context.Context, connection netpoll.Connec
 	return err
 }
 
-func (s *ServerRequest) onMessage(sconn *NetPollConnection, typ packet.Type, id int32, sid int64, bdata []byte) (err error) {
+func (s *ServerRequest) onMessage(sconn *NetPollConnection, typ packet.Type, id int32, sid int64, seq uint32, bdata []byte) (err error) {
 	switch typ {
 	case packet.Heartbeat:
 	case packet.Data:
 		if model.IsLocalHandler(id) {
-			err = s.modelManager.DispatchAsync(sconn, id, bdata)
-		} else {
-			err = remoteCall(sconn, sconn.PackCodec, packet.NewInternal(packet.InternalData, id, sid, bdata), defaultNodeAgent.getGroutes(id))
+			err = s.modelManager.DispatchAsync(sconn, id, seq, bdata)
+			break
 		}
+		var held bool
+		if held, err = sconn.hold(id, sid, seq, bdata); err != nil || held {
+			break
+		}
+		err = remoteCall(sconn, sconn.PackCodec, packet.NewWithHeader(packet.Header{Type: packet.InternalData, ID: id, SID: sid, Seq: seq}, bdata), defaultNodeAgent.getGroutes(id))
 	case packet.Connection:
 		var pb = &N2MOnConnection{}
 		if err := proto.Unmarshal(bdata, pb); err != nil {
 			return fmt.Errorf("[ServerRequest/onMessage] Type[%d] ConnID[%d] proto Unmarshal %w", typ, sconn.ID(), err)
 		}
 		s.connManager.RemoveByID(sconn.ID())
+		sconn.markNode()
 		defaultNodeAgent.storeNodeConn(pb.ID, sconn)
 		logx.Dbg.Printf("[ServerRequest/onMessage] Type[%d]  %v", typ, pb)
 		err = sconn.SendTypePb(packet.Connection, &M2NOnConnection{
@@ -116,15 +133,8 @@ func (s *ServerRequest) onMessage(sconn *NetPollConnection, typ packet.Type, id
 		if err := proto.Unmarshal(bdata, &pb); err != nil {
 			return fmt.Errorf("[ServerRequest/onMessage] Type[%d] ConnID[%d] proto Unmarshal %w", typ, sconn.ID(), err)
 		}
-		conn, ok := s.connManager.GetByID(pb.SessionID)
-		if !ok {
-			conn = newAcceptor(session.NewNetworkEntities(pb.SessionID, pb.UID), defaultNodeAgent.svr)
-			s.connManager.StoreSession(conn)
-		}
-		for name, id := range pb.GetServers() {
-			conn.BindServers(name, id)
-		}
-		logx.Dbg.Printf("[ServerRequest/onMessage] Type[%d] ConnID[%d] SessionID: %d %v", typ, sconn.ID(), pb.SessionID, conn.Servers())
+		bindAcceptor(s.connManager, &pb)
+		logx.Dbg.Printf("[ServerRequest/onMessage] Type[%d] ConnID[%d] SessionID: %d %v", typ, sconn.ID(), pb.SessionID, pb.GetServers())
 	case packet.InternalData:
 		if !model.IsLocalHandler(id) {
 			return fmt.Errorf("[ServerRequest/onMessage] Type[%d] ConnID[%d] MessageID: %d not found", typ, sconn.ID(), id)
@@ -133,29 +143,42 @@ func (s *ServerRequest) onMessage(sconn *NetPollConnection, typ packet.Type, id
 		if !ok {
 			return fmt.Errorf("[ServerRequest/onMessage] Type[%d] ConnID[%d] SessionID: %d not found", typ, sconn.ID(), sid)
 		}
-		err = s.modelManager.DispatchAsync(conn, id, bdata)
+		err = s.modelManager.DispatchAsync(conn, id, seq, bdata)
 	case packet.ClientData:
 		conn, ok := s.connManager.GetByID(sid)
 		if !ok {
 			return fmt.Errorf("[ServerRequest/onMessage] Type[%d] ConnID[%d] SessionID: %d not found", typ, sconn.ID(), sid)
 		}
-		conn1, ok := conn.(sender)
-		if !ok {
+		if _, ok := conn.(sender); !ok {
 			return fmt.Errorf("[ServerRequest/onMessage] Type[%d] ConnID[%d] 反射 SendData", typ, sconn.ID())
 		}
-		err = conn1.SendData(bdata)
+		err = forwardClient(conn, bdata)
+	case packet.Request:
+		var pb N2MRequest
+		if err := proto.Unmarshal(bdata, &pb); err != nil {
+			return fmt.Errorf("[ServerRequest/onMessage] Type[%d] ConnID[%d] Unmarshal %w", typ, sconn.ID(), err)
+		}
+		err = defaultRPC.serve(sconn, &pb)
+	case packet.Response:
+		var pb N2MResponse
+		if err := proto.Unmarshal(bdata, &pb); err != nil {
+			return fmt.Errorf("[ServerRequest/onMessage] Type[%d] ConnID[%d] Unmarshal %w", typ, sconn.ID(), err)
+		}
+		err = defaultRPC.resolve(&pb)
 	case packet.NotifyData:
 		var pb N2MNotify
 		if err := proto.Unmarshal(bdata, &pb); err != nil {
 			return fmt.Errorf("[ServerRequest/onMessage] Type[%d] ConnID[%d] Unmarshal %w", typ, sconn.ID(), err)
 		}
+		if pb.Group != "" {
+			return defaultGroups.deliver(pb.Group, pb.Plyload)
+		}
 		if len(pb.SessionID) == 0 {
 			return s.connManager.Range(func(s session.Session) error {
-				conn1, ok := s.(sender)
-				if !ok {
+				if _, ok := s.(sender); !ok {
 					return fmt.Errorf("[ServerRequest/onMessage] Range Type[%d] 反射 SendData", typ)
 				}
-				return conn1.SendData(pb.Plyload)
+				return forwardClient(s, pb.Plyload)
 			})
 		}
 		var errs []error
@@ -165,12 +188,11 @@ func (s *ServerRequest) onMessage(sconn *NetPollConnection, typ packet.Type, id
 				errs = append(errs, fmt.Errorf("[ServerRequest/onMessage] Type[%d] ConnID[%d] SessionID: %d not found", typ, sconn.ID(), sid))
 				continue
 			}
-			conn1, ok := conn.(sender)
-			if !ok {
+			if _, ok := conn.(sender); !ok {
 				errs = append(errs, fmt.Errorf("[ServerRequest/onMessage] Type[%d] 反射 SendData", typ))
 				continue
 			}
-			errs = append(errs, conn1.SendData(pb.Plyload))
+			errs = append(errs, forwardClient(conn, pb.Plyload))
 		}
 		if err = errors.Join(errs...); err != nil {
 			return fmt.Errorf("[ServerRequest/onMessage] Type[%d] Notify error: %w", typ, err)
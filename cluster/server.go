@@ -36,10 +36,14 @@ type server struct {
 }
 
 func NewServer() Server {
+	return NewServerWithClock(scheduler.SystemClock)
+}
+
+func NewServerWithClock(clock scheduler.Clock) Server {
 	s := &server{
 		connManager:  connmannger.NewConnManager(),
 		modelManager: model.DefaultModelManager,
-		scheduler:    scheduler.NewScheduler(),
+		scheduler:    scheduler.NewSchedulerWithClock(0, 0, clock),
 		workMessage:  newWorkMessage(),
 	}
 
@@ -58,6 +62,9 @@ func (s *server) Scheduler() *scheduler.Scheduler { return s.scheduler }
 func (s *server) WorkMessage() *WorkMessage { return s.workMessage }
 
 func (s *server) Listen(addr string) error {
+	if err := s.modelManager.Start(); err != nil {
+		return err
+	}
 	logx.Inf.Printf("[START] TCP Server listener at Addr: %s is starting", addr)
 	ln, err := netpoll.CreateListener("tcp", addr)
 	if err != nil {
@@ -93,5 +100,6 @@ func (s *server) Shutdown(ctx context.Context) error {
 	s.modelManager.Stop()
 	s.connManager.Range(func(s session.Session) error { return s.Close() })
 	defaultNodeAgent.connManager.Range(func(s session.Session) error { return s.Close() })
+	defer logx.Flush()
 	return s.poll.Shutdown(xctx)
 }
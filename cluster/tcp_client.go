@@ -8,7 +8,9 @@ import (
 	"infra-foundation/packet"
 	"infra-foundation/protomessage"
 	"infra-foundation/scheduler"
+	"infra-foundation/session"
 	"net"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -18,31 +20,53 @@ import (
 
 type handler func(*TCPClient, protomessage.ProtoMessage)
 
+type errorHandler func(t *TCPClient, id int32, err *session.CodeError)
+
+type pendingCall struct {
+	seq    uint32
+	respID int32
+	resp   protomessage.ProtoMessage
+	done   chan error
+}
+
 type TCPClient struct {
 	conn              net.Conn
 	codec             *packet.PackCodec
 	handlers          map[int32]handler
 	msgs              map[int32]protomessage.ProtoMessage
 	handlersrw        sync.RWMutex
+	onError           errorHandler
+	calls             []*pendingCall
+	callsmu           sync.Mutex
+	seq               atomic.Uint32
 	closed            atomic.Bool
 	writeC            chan []byte
 	timerID           scheduler.TimerID
 	scheduler         *scheduler.Scheduler
 	heartbeatTime     time.Duration
 	lastHeartbeatTime atomic.Int64
+	compress          bool
+	encrypt           bool
+	handshaken        chan struct{}
+	handshakeOnce     sync.Once
 	wg                sync.WaitGroup
 	ctx               context.Context
 	cancel            context.CancelFunc
 }
 
 func NewTCPClient() *TCPClient {
+	return NewTCPClientWithClock(scheduler.SystemClock)
+}
+
+func NewTCPClientWithClock(clock scheduler.Clock) *TCPClient {
 	t := &TCPClient{
-		codec:         packet.NewPackCodec(),
+		codec:         packet.NewPackCodecWithVersion(packet.V2),
 		handlers:      map[int32]handler{},
 		msgs:          map[int32]protomessage.ProtoMessage{},
 		writeC:        make(chan []byte, 1<<8),
-		scheduler:     scheduler.NewScheduler(),
+		scheduler:     scheduler.NewSchedulerWithClock(0, 0, clock),
 		heartbeatTime: time.Second * 3,
+		handshaken:    make(chan struct{}),
 	}
 	t.ctx, t.cancel = context.WithCancel(context.TODO())
 	return t
@@ -56,25 +80,153 @@ func (t *TCPClient) HeartbeatAt() int64 { return t.lastHeartbeatTime.Load() }
 
 func (t *TCPClient) SetHeartbeatAt(now int64) { t.lastHeartbeatTime.Store(now) }
 
-func (t *TCPClient) RefreshHeartbeat() { t.SetHeartbeatAt(time.Now().Unix()) }
+func (t *TCPClient) RefreshHeartbeat() { t.SetHeartbeatAt(t.scheduler.Clock().Now().Unix()) }
 
 func (t *TCPClient) RegisterHandler(pb protomessage.ProtoMessage, handl handler) {
+	if reg, ok := protomessage.Lookup(pb.MessageID()); ok && reg.ProtoReflect().Descriptor() != pb.ProtoReflect().Descriptor() {
+		logx.Err.Printf("[TCPClient/RegisterHandler] message[%d] %s collides with %s", pb.MessageID(), pb.MessageName(), reg.MessageName())
+	}
 	t.handlersrw.Lock()
 	t.handlers[pb.MessageID()] = handl
 	t.msgs[pb.MessageID()] = pb
 	t.handlersrw.Unlock()
 }
 
+// OnError sets the handler for error packets that no Call is waiting for.
+func (t *TCPClient) OnError(fn errorHandler) {
+	t.handlersrw.Lock()
+	t.onError = fn
+	t.handlersrw.Unlock()
+}
+
+// Call sends req under a fresh header sequence and waits for the packet the
+// server answers it with, which carries the same sequence. A reply is
+// unmarshaled into resp, an error packet is returned as a *session.CodeError.
+// Packets the server pushes carry no sequence and never complete a Call.
+func (t *TCPClient) Call(ctx context.Context, req, resp protomessage.ProtoMessage) error {
+	if t.IsClosed() {
+		return errors.New("[TCPClient/Call] connection closed")
+	}
+	pbdata, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("[TCPClient/Call] Marshal %w", err)
+	}
+	seq := t.seq.Add(1)
+	if seq == 0 {
+		seq = t.seq.Add(1)
+	}
+	call := &pendingCall{seq: seq, respID: resp.MessageID(), resp: resp, done: make(chan error, 1)}
+	t.callsmu.Lock()
+	t.calls = append(t.calls, call)
+	t.callsmu.Unlock()
+	bdata, err := t.codec.PackHeader(packet.Header{Type: packet.Data, ID: req.MessageID(), Seq: seq}, pbdata)
+	if err == nil {
+		err = t.SendData(bdata)
+	}
+	if err != nil {
+		t.removeCall(call)
+		return fmt.Errorf("[TCPClient/Call] %w", err)
+	}
+	select {
+	case err := <-call.done:
+		return err
+	case <-ctx.Done():
+		t.removeCall(call)
+		return ctx.Err()
+	}
+}
+
+func (t *TCPClient) removeCall(call *pendingCall) {
+	t.callsmu.Lock()
+	t.calls = slices.DeleteFunc(t.calls, func(c *pendingCall) bool { return c == call })
+	t.callsmu.Unlock()
+}
+
+// takeCall removes the call waiting for the answer with sequence seq.
+func (t *TCPClient) takeCall(seq uint32) *pendingCall {
+	if seq == 0 {
+		return nil
+	}
+	t.callsmu.Lock()
+	defer t.callsmu.Unlock()
+	i := slices.IndexFunc(t.calls, func(c *pendingCall) bool { return c.seq == seq })
+	if i < 0 {
+		return nil
+	}
+	call := t.calls[i]
+	t.calls = slices.Delete(t.calls, i, i+1)
+	return call
+}
+
+func (t *TCPClient) onErrorPacket(pk *packet.Packet) {
+	var pb M2CError
+	if err := proto.Unmarshal(pk.Data(), &pb); err != nil {
+		logx.Err.Printf("[TCPClient/ReaderLoop] error packet[%d] proto Unmarshal error: %v", pk.ID(), err)
+		return
+	}
+	id, cerr := pk.ID(), session.NewError(pb.Code, pb.Message)
+	if call := t.takeCall(pk.Seq()); call != nil {
+		call.done <- cerr
+		return
+	}
+	t.handlersrw.RLock()
+	fn := t.onError
+	t.handlersrw.RUnlock()
+	if fn == nil {
+		logx.Err.Printf("[TCPClient/ReaderLoop] message[%d] %v", id, cerr)
+		return
+	}
+	t.scheduler.PushTask(func() { fn(t, id, cerr) })
+}
+
 func (t *TCPClient) DialConnection(addr string) error {
-	var err error
-	t.conn, err = net.Dial("tcp", addr)
+	conn, err := net.Dial("tcp", addr)
 	if err != nil {
 		return err
 	}
+	t.start(conn)
+	if !t.encrypt {
+		return nil
+	}
+	select {
+	case <-t.handshaken:
+		return nil
+	case <-time.After(t.heartbeatTime):
+		t.Close()
+		return fmt.Errorf("[TCPClient/DialConnection] %s key exchange timeout", addr)
+	}
+}
+
+// EnableCompression makes the client offer algs to the server when it
+// connects, compressing payloads of at least threshold bytes.
+func (t *TCPClient) EnableCompression(threshold int, algs ...packet.Compression) {
+	t.codec.EnableCompression(threshold, algs...)
+	t.compress = true
+}
+
+// EnableEncryption makes the client exchange a key with the server when it
+// connects and encrypt every packet after. DialConnection then returns once
+// the exchange completed.
+func (t *TCPClient) EnableEncryption() {
+	t.codec.EnableEncryption()
+	t.encrypt = true
+}
+
+func (t *TCPClient) start(conn net.Conn) {
+	t.conn = conn
 	t.timerID, _ = t.scheduler.PushEvery(t.heartbeatTime, t.sendHeartbeat)
 	t.wg.Go(t.writeLoop)
 	t.wg.Go(t.readerLoop)
-	return err
+	if !t.compress && !t.encrypt {
+		return
+	}
+	handshake, err := t.codec.Handshake()
+	if err == nil {
+		err = t.SendData(handshake)
+	}
+	if err != nil {
+		logx.Err.Printf("[TCPClient/start] handshake %v", err)
+	}
 }
 
 func (t *TCPClient) Send(pb protomessage.ProtoMessage) error {
@@ -122,22 +274,53 @@ func (t *TCPClient) SendPack(pack *packet.Packet) error {
 	return t.SendData(bdata)
 }
 
+// readerLoop decodes the packets of the server one at a time, so that the
+// answer to the handshake is accepted before the packets it compresses or
+// encrypts are decoded.
 func (t *TCPClient) readerLoop() {
 	var bdata = make([]byte, 2048)
 	for {
 		n, err := t.conn.Read(bdata)
 		if err != nil {
-			logx.Err.Println(err)
+			if !t.IsClosed() {
+				t.abort(err)
+			}
 			return
 		}
-		pks, err := t.codec.Unpack(bdata[:n])
-		if err != nil {
-			logx.Dbg.Println(err)
+		if err = t.codec.Feed(bdata[:n]); err != nil {
+			t.abort(err)
 			return
 		}
-		for _, pk := range pks {
+		for {
+			pk, err := t.codec.Next()
+			if err != nil {
+				t.abort(err)
+				return
+			}
+			if pk == nil {
+				break
+			}
 			switch pk.Type() {
+			case packet.Heartbeat:
+				if err = t.codec.Accept(pk, t.SendData); err != nil {
+					logx.Err.Printf("[TCPClient/ReaderLoop] handshake %v", err)
+					pk.Free()
+					return
+				}
+				if t.encrypt && t.codec.Encrypted() {
+					t.handshakeOnce.Do(func() { close(t.handshaken) })
+				}
+			case packet.Error:
+				t.onErrorPacket(pk)
 			case packet.Data:
+				if call := t.takeCall(pk.Seq()); call != nil {
+					if pk.ID() != call.respID {
+						call.done <- fmt.Errorf("[TCPClient/Call] replied message[%d], want %d", pk.ID(), call.respID)
+						break
+					}
+					call.done <- proto.Unmarshal(pk.Data(), call.resp)
+					break
+				}
 				t.handlersrw.RLock()
 				pb, ok1 := t.msgs[pk.ID()]
 				hd, ok2 := t.handlers[pk.ID()]
@@ -161,20 +344,35 @@ func (t *TCPClient) readerLoop() {
 	}
 }
 
+// abort ends a connection the reader cannot go on with: the stream is closed
+// so the writer stops too, and the pending calls fail with err.
+func (t *TCPClient) abort(err error) {
+	logx.Err.Printf("[TCPClient/ReaderLoop] %v", err)
+	t.conn.Close()
+	t.callsmu.Lock()
+	calls := t.calls
+	t.calls = nil
+	t.callsmu.Unlock()
+	for _, call := range calls {
+		call.done <- fmt.Errorf("[TCPClient/Call] %w", err)
+	}
+}
+
 func (t *TCPClient) Close() error {
 	if !t.SetClosed() {
 		return nil
 	}
 	t.cancel()
+	err := t.conn.Close()
 	t.wg.Wait()
 	close(t.writeC)
 	t.scheduler.CancelTimer(t.timerID)
 	t.scheduler.Stop()
-	return t.conn.Close()
+	return err
 }
 
 func (t *TCPClient) sendHeartbeat() {
-	now := time.Now().Unix()
+	now := t.scheduler.Clock().Now().Unix()
 	if t.HeartbeatAt()+int64(t.heartbeatTime.Seconds()) > now {
 		return
 	}
@@ -194,6 +392,11 @@ func (t *TCPClient) writeLoop() {
 			if !ok {
 				return
 			}
+			bdata, err := t.codec.Seal(bdata)
+			if err != nil {
+				logx.Err.Printf("[TCPClient/writeLoop] seal error: %v", err)
+				return
+			}
 			for off := 0; off < len(bdata); {
 				n, err := t.conn.Write(bdata[off:])
 				if err != nil {
@@ -0,0 +1,257 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"infra-foundation/example/protos"
+	"infra-foundation/packet"
+	"infra-foundation/protomessage"
+	"infra-foundation/scheduler"
+	"infra-foundation/session"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTCPClientHeartbeat(t *testing.T) {
+	clock := scheduler.NewFakeClock(time.Unix(1000, 0))
+	client := NewTCPClientWithClock(clock)
+	local, remote := net.Pipe()
+	client.start(local)
+	defer client.Close()
+
+	got := make(chan *packet.Packet, 4)
+	go func() {
+		codec := packet.NewPackCodec()
+		buf := make([]byte, 64)
+		for {
+			n, err := remote.Read(buf)
+			if err != nil {
+				return
+			}
+			pks, _ := codec.Unpack(buf[:n])
+			for _, pk := range pks {
+				got <- pk
+			}
+		}
+	}()
+
+	clock.Advance(time.Second)
+	client.scheduler.Sync()
+	select {
+	case pk := <-got:
+		t.Fatalf("unexpected packet %v", pk)
+	default:
+	}
+
+	clock.Advance(2 * time.Second)
+	client.scheduler.Sync()
+	select {
+	case pk := <-got:
+		if pk.Type() != packet.Heartbeat {
+			t.Fatalf("type = %d, want heartbeat", pk.Type())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("heartbeat not sent")
+	}
+	if client.HeartbeatAt() != clock.Now().Unix() {
+		t.Fatalf("HeartbeatAt = %d, want %d", client.HeartbeatAt(), clock.Now().Unix())
+	}
+}
+
+func TestTCPClientCallError(t *testing.T) {
+	client := NewTCPClient()
+	local, remote := net.Pipe()
+	client.start(local)
+	defer client.Close()
+
+	codec := packet.NewPackCodec()
+	go func() {
+		buf := make([]byte, 256)
+		for i := 0; ; i++ {
+			n, err := remote.Read(buf)
+			if err != nil {
+				return
+			}
+			pks, _ := codec.Unpack(buf[:n])
+			for _, pk := range pks {
+				if pk.Type() != packet.Data {
+					continue
+				}
+				var reply []byte
+				if i == 0 {
+					data, _ := proto.Marshal(&M2CError{Code: 42, Message: "bad name"})
+					reply, _ = codec.PackHeader(packet.Header{Type: packet.Error, ID: pk.ID(), Seq: pk.Seq()}, data)
+				} else {
+					// a push of the reply message arrives first and must not
+					// complete the call
+					data, _ := proto.Marshal(&protos.S2CLogin{Name: "push"})
+					reply, _ = codec.Pack(packet.Data, (&protos.S2CLogin{}).MessageID(), 0, data)
+					data, _ = proto.Marshal(&protos.S2CLogin{Name: "ok"})
+					answer, _ := codec.PackHeader(packet.Header{Type: packet.Data, ID: (&protos.S2CLogin{}).MessageID(), Seq: pk.Seq()}, data)
+					reply = append(reply, answer...)
+				}
+				remote.Write(reply)
+			}
+		}
+	}()
+
+	pushed := make(chan string, 1)
+	client.RegisterHandler(&protos.S2CLogin{}, func(_ *TCPClient, pb protomessage.ProtoMessage) {
+		pushed <- pb.(*protos.S2CLogin).Name
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := client.Call(ctx, &protos.C2SLogin{Name: "x"}, &protos.S2CLogin{})
+	cerr, ok := errors.AsType[*session.CodeError](err)
+	if !ok || cerr.Code != 42 || cerr.Message != "bad name" {
+		t.Fatalf("err = %v", err)
+	}
+	resp := &protos.S2CLogin{}
+	if err = client.Call(ctx, &protos.C2SLogin{Name: "y"}, resp); err != nil || resp.Name != "ok" {
+		t.Fatalf("resp = %v err = %v", resp, err)
+	}
+	select {
+	case name := <-pushed:
+		if name != "push" {
+			t.Fatalf("pushed = %q", name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("push not handled")
+	}
+}
+
+func TestTCPClientEncryption(t *testing.T) {
+	client := NewTCPClient()
+	client.EnableEncryption()
+	local, remote := net.Pipe()
+	client.start(local)
+	defer client.Close()
+
+	codec := packet.NewPackCodec()
+	write := func(b []byte) error {
+		sealed, err := codec.Seal(b)
+		if err == nil {
+			_, err = remote.Write(sealed)
+		}
+		return err
+	}
+	leaked := make(chan bool, 1)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := remote.Read(buf)
+			if err != nil {
+				return
+			}
+			if bytes.Contains(buf[:n], []byte("hidden")) {
+				leaked <- true
+			}
+			pks, err := codec.Unpack(buf[:n])
+			if err != nil {
+				return
+			}
+			for _, pk := range pks {
+				switch pk.Type() {
+				case packet.Heartbeat:
+					codec.Accept(pk, write)
+				case packet.Data:
+					var req protos.C2SLogin
+					proto.Unmarshal(pk.Data(), &req)
+					data, _ := proto.Marshal(&protos.S2CLogin{Name: req.Name})
+					reply, _ := codec.PackHeader(packet.Header{Type: packet.Data, ID: (&protos.S2CLogin{}).MessageID(), Seq: pk.Seq()}, data)
+					write(reply)
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-client.handshaken:
+	case <-time.After(time.Second):
+		t.Fatal("key exchange not completed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp := &protos.S2CLogin{}
+	if err := client.Call(ctx, &protos.C2SLogin{Name: "hidden"}, resp); err != nil || resp.Name != "hidden" {
+		t.Fatalf("resp = %v err = %v", resp, err)
+	}
+	select {
+	case <-leaked:
+		t.Fatal("payload sent in plaintext")
+	default:
+	}
+}
+
+func TestTCPClientCompressedReplyWithAnswer(t *testing.T) {
+	client := NewTCPClient()
+	client.EnableCompression(64)
+	local, remote := net.Pipe()
+	client.start(local)
+	defer client.Close()
+
+	codec := packet.NewPackCodec()
+	codec.EnableCompression(64, packet.CompressionDeflate)
+	name := strings.Repeat("compressed ", 50)
+	go func() {
+		// the answer to the offer is held back and written in front of the
+		// compressed reply, so the client reads both at once
+		var answer []byte
+		buf := make([]byte, 2048)
+		for {
+			n, err := remote.Read(buf)
+			if err != nil {
+				return
+			}
+			pks, _ := codec.Unpack(buf[:n])
+			for _, pk := range pks {
+				switch pk.Type() {
+				case packet.Heartbeat:
+					codec.Accept(pk, func(b []byte) error { answer = b; return nil })
+				case packet.Data:
+					data, _ := proto.Marshal(&protos.S2CLogin{Name: name})
+					reply, _ := codec.PackHeader(packet.Header{Type: packet.Data, ID: (&protos.S2CLogin{}).MessageID(), Seq: pk.Seq()}, data)
+					remote.Write(append(answer, reply...))
+				}
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp := &protos.S2CLogin{}
+	if err := client.Call(ctx, &protos.C2SLogin{Name: "x"}, resp); err != nil || resp.Name != name {
+		t.Fatalf("resp = %v err = %v", resp, err)
+	}
+	if client.codec.Compression() != packet.CompressionDeflate {
+		t.Fatalf("negotiated %d", client.codec.Compression())
+	}
+}
+
+func TestTCPClientBrokenStreamFailsCalls(t *testing.T) {
+	client := NewTCPClient()
+	local, remote := net.Pipe()
+	client.start(local)
+	defer client.Close()
+
+	go func() {
+		buf := make([]byte, 256)
+		if _, err := remote.Read(buf); err != nil {
+			return
+		}
+		remote.Write([]byte{0, 0, 0, 1, byte(packet.Data)})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := client.Call(ctx, &protos.C2SLogin{Name: "x"}, &protos.S2CLogin{})
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+}
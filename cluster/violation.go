@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"errors"
+	"infra-foundation/logx"
+	"infra-foundation/packet"
+	"sync/atomic"
+)
+
+const defaultMaxProtocolViolations = 3
+
+var (
+	maxProtocolViolations atomic.Int32
+	protocolViolations    atomic.Uint64
+)
+
+func init() {
+	maxProtocolViolations.Store(defaultMaxProtocolViolations)
+}
+
+// SetMaxProtocolViolations sets the number of malformed packets a connection
+// may send before it is closed.
+func SetMaxProtocolViolations(n int) {
+	maxProtocolViolations.Store(int32(max(n, 1)))
+}
+
+// ProtocolViolations returns the number of malformed packets received on
+// every connection.
+func ProtocolViolations() uint64 {
+	return protocolViolations.Load()
+}
+
+// violate records a malformed packet from the peer of c and closes c once it
+// reached the limit, or at once when fatal or when the packet failed
+// decryption: the stream cannot be trusted after them. It reports whether c
+// was closed.
+func (c *Connection) violate(err error, fatal bool) bool {
+	protocolViolations.Add(1)
+	n := c.violations.Add(1)
+	if !fatal && !errors.Is(err, packet.ErrEncryption) && n < maxProtocolViolations.Load() {
+		return false
+	}
+	logx.Err.Printf("[Connection/violate] close %d after %d protocol violations: %v", c.ID(), n, err)
+	if c.Connection != nil {
+		_ = c.Connection.Close()
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package cluster
+
+import (
+	"context"
+	"infra-foundation/packet"
+	"testing"
+
+	"github.com/cloudwego/netpoll"
+)
+
+type closeRecorder struct {
+	netpoll.Connection
+	closed int
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed++
+	return nil
+}
+
+// readerConn is a connection reading from a fixed reader.
+type readerConn struct {
+	closeRecorder
+	r netpoll.Reader
+}
+
+func (c *readerConn) Reader() netpoll.Reader { return c.r }
+
+// brokenReader fails every read as a reset connection does.
+type brokenReader struct{ netpoll.Reader }
+
+func (brokenReader) Peek(int) ([]byte, error) { return nil, netpoll.ErrConnClosed }
+
+func TestProtocolViolations(t *testing.T) {
+	SetMaxProtocolViolations(2)
+	defer SetMaxProtocolViolations(defaultMaxProtocolViolations)
+
+	nc := &closeRecorder{}
+	c := NewConnection(nc, 1, -1)
+	defer c.Close()
+	before := ProtocolViolations()
+	bad := &packet.ProtocolError{Type: packet.Data, Err: packet.ErrBadExtension}
+	if c.violate(bad, false) || nc.closed != 0 {
+		t.Fatal("closed on the first malformed packet")
+	}
+	if !c.violate(bad, false) || nc.closed != 1 {
+		t.Fatal("not closed at the limit")
+	}
+	if ProtocolViolations() != before+2 {
+		t.Fatalf("violations = %d", ProtocolViolations()-before)
+	}
+
+	nc2 := &closeRecorder{}
+	c2 := NewConnection(nc2, 2, -1)
+	defer c2.Close()
+	if !c2.violate(&packet.ProtocolError{Err: packet.ErrEncryption}, false) || nc2.closed != 1 {
+		t.Fatal("decryption failure did not close")
+	}
+}
+
+func TestReadErrorsAreNotViolations(t *testing.T) {
+	c := NewClientConnection(NewServer())
+	nc := &readerConn{r: brokenReader{}}
+	c.Connection = NewConnection(nc, 3, -1)
+	defer c.Connection.Close()
+
+	before := ProtocolViolations()
+	if err := c.OnRequest(context.Background(), nc); err == nil {
+		t.Fatal("read error not returned")
+	}
+	if ProtocolViolations() != before || nc.closed != 0 {
+		t.Fatalf("read error counted: violations = %d closed = %d", ProtocolViolations()-before, nc.closed)
+	}
+
+	bad := netpoll.NewLinkBuffer(8)
+	bad.WriteBinary([]byte{0, 0, 0, 1, byte(packet.Data)})
+	bad.Flush()
+	nc.r = bad
+	if err := c.OnRequest(context.Background(), nc); err == nil {
+		t.Fatal("malformed frame accepted")
+	}
+	if ProtocolViolations() != before+1 || nc.closed != 1 {
+		t.Fatalf("malformed frame: violations = %d closed = %d", ProtocolViolations()-before, nc.closed)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// routeServerField is the field number of the (route.server) FileOptions
+// extension declared in route.proto.
+const routeServerField = 50001
+
+const (
+	protomessagePackage = protogen.GoImportPath("infra-foundation/protomessage")
+	sessionPackage      = protogen.GoImportPath("infra-foundation/session")
+)
+
+type config struct {
+	registry bool
+	handlers bool
+}
+
+type messageMeta struct {
+	message *protogen.Message
+	id      int32
+}
+
+func generate(gen *protogen.Plugin, cfg config) error {
+	owners := map[int32]string{}
+	for _, f := range gen.Files {
+		if !f.Generate || len(f.Messages) == 0 {
+			continue
+		}
+		metas, err := fileMetas(f)
+		if err != nil {
+			return err
+		}
+		for _, m := range metas {
+			name := string(m.message.Desc.FullName())
+			if other, ok := owners[m.id]; ok {
+				return fmt.Errorf("message ID %d used by both %s and %s", m.id, other, name)
+			}
+			owners[m.id] = name
+		}
+		node, mode, err := fileRoute(f)
+		if err != nil {
+			return err
+		}
+		genFile(gen, f, metas, node, mode, cfg)
+	}
+	return nil
+}
+
+func fileMetas(f *protogen.File) ([]messageMeta, error) {
+	var metas []messageMeta
+	var next int32
+	for _, m := range f.Messages {
+		id, ok, err := directiveInt(string(m.Comments.Leading), "@id")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s %w", f.Desc.Path(), m.Desc.Name(), err)
+		}
+		if !ok {
+			if next == 0 {
+				return nil, fmt.Errorf("%s: %s has no @id directive", f.Desc.Path(), m.Desc.Name())
+			}
+			id = next
+		}
+		metas = append(metas, messageMeta{message: m, id: id})
+		next = id + 1
+	}
+	return metas, nil
+}
+
+func fileRoute(f *protogen.File) (node, mode string, err error) {
+	route := routeOption(f.Desc.Options())
+	if route == "" {
+		for _, path := range []protoreflect.SourcePath{{12}, {2}} {
+			if v, ok := directive(f.Desc.SourceLocations().ByPath(path).LeadingComments, "@route"); ok {
+				route = v
+				break
+			}
+		}
+	}
+	if route == "" {
+		return "", "", nil
+	}
+	node, mode, ok := strings.Cut(route, ".")
+	if !ok || node == "" || mode == "" {
+		return "", "", fmt.Errorf("%s: route %q is not NODE.model", f.Desc.Path(), route)
+	}
+	return node, mode, nil
+}
+
+func routeOption(opts protoreflect.ProtoMessage) string {
+	fo, ok := opts.(*descriptorpb.FileOptions)
+	if !ok || fo == nil {
+		return ""
+	}
+	b := fo.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return ""
+		}
+		b = b[n:]
+		if num == routeServerField && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return ""
+			}
+			return string(v)
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return ""
+		}
+		b = b[n:]
+	}
+	return ""
+}
+
+func directive(comments, name string) (string, bool) {
+	for line := range strings.Lines(comments) {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == name {
+			return fields[1], true
+		}
+	}
+	return "", false
+}
+
+func directiveInt(comments, name string) (int32, bool, error) {
+	v, ok := directive(comments, name)
+	if !ok {
+		return 0, false, nil
+	}
+	id, err := strconv.ParseInt(v, 10, 32)
+	if err != nil || id <= 0 {
+		return 0, false, fmt.Errorf("invalid %s %q", name, v)
+	}
+	return int32(id), true, nil
+}
+
+func genFile(gen *protogen.Plugin, f *protogen.File, metas []messageMeta, node, mode string, cfg config) {
+	g := gen.NewGeneratedFile(f.GeneratedFilenamePrefix+"_msgmeta.pb.go", f.GoImportPath)
+	g.P("// Code generated by protoc-gen-msgmeta. DO NOT EDIT.")
+	g.P("// source: ", f.Desc.Path())
+	g.P()
+	g.P("package ", f.GoPackageName)
+	g.P()
+	for _, m := range metas {
+		name := m.message.GoIdent.GoName
+		g.P("func (x *", name, ") MessageID() int32 { return ", m.id, " }")
+		g.P("func (x *", name, ") MessageName() string { return ", strconv.Quote(string(m.message.Desc.Name())), " }")
+		g.P("func (x *", name, ") NodeName() string { return ", strconv.Quote(node), " }")
+		g.P("func (x *", name, ") ModeName() string { return ", strconv.Quote(mode), " }")
+	}
+
+	if cfg.registry {
+		g.P()
+		g.P("func init() {")
+		g.P(protomessagePackage.Ident("Register"), "(")
+		for _, m := range metas {
+			g.P("&", m.message.GoIdent, "{},")
+		}
+		g.P(")")
+		g.P("}")
+	}
+
+	if cfg.handlers {
+		protoMessage := protomessagePackage.Ident("ProtoMessage")
+		sess := sessionPackage.Ident("Session")
+		for _, m := range metas {
+			name := m.message.GoIdent.GoName
+			g.P()
+			g.P("func Handle", name, "(fn func(", sess, ", *", name, ")) ", sessionPackage.Ident("HandlerFunc"), " {")
+			g.P("return func(s ", sess, ", pb ", protoMessage, ") { fn(s, pb.(*", name, ")) }")
+			g.P("}")
+			g.P()
+			g.P("func Reply", name, "(fn func(", sess, ", *", name, ") (", protoMessage, ", error)) ", sessionPackage.Ident("ReplyHandlerFunc"), " {")
+			g.P("return func(s ", sess, ", pb ", protoMessage, ") (", protoMessage, ", error) { return fn(s, pb.(*", name, ")) }")
+			g.P("}")
+		}
+	}
+}
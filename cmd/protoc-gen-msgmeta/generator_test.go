@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func testFile(name, route string, ids map[int]string, messages ...string) *descriptorpb.FileDescriptorProto {
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:           proto.String(name),
+		Package:        proto.String(strings.TrimSuffix(name, ".proto")),
+		Syntax:         proto.String("proto3"),
+		Options:        &descriptorpb.FileOptions{GoPackage: proto.String("example.com/" + strings.TrimSuffix(name, ".proto"))},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+	}
+	if route != "" {
+		b := protowire.AppendTag(nil, routeServerField, protowire.BytesType)
+		fd.Options.ProtoReflect().SetUnknown(protowire.AppendString(b, route))
+	}
+	for i, m := range messages {
+		fd.MessageType = append(fd.MessageType, &descriptorpb.DescriptorProto{Name: proto.String(m)})
+		if c, ok := ids[i]; ok {
+			fd.SourceCodeInfo.Location = append(fd.SourceCodeInfo.Location, &descriptorpb.SourceCodeInfo_Location{
+				Path: []int32{4, int32(i)}, Span: []int32{0, 0, 0}, LeadingComments: proto.String(c),
+			})
+		}
+	}
+	return fd
+}
+
+func run(t *testing.T, files ...*descriptorpb.FileDescriptorProto) *pluginpb.CodeGeneratorResponse {
+	t.Helper()
+	req := &pluginpb.CodeGeneratorRequest{ProtoFile: files}
+	for _, f := range files {
+		req.FileToGenerate = append(req.FileToGenerate, f.GetName())
+	}
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = generate(gen, config{registry: true, handlers: true}); err != nil {
+		gen.Error(err)
+	}
+	return gen.Response()
+}
+
+func TestGenerate(t *testing.T) {
+	resp := run(t, testFile("user.proto", "GAME.user", map[int]string{0: " @id 100\n"}, "C2SLogin", "S2CLogin"))
+	if resp.Error != nil {
+		t.Fatal(*resp.Error)
+	}
+	if len(resp.File) != 1 || resp.File[0].GetName() != "example.com/user/user_msgmeta.pb.go" {
+		t.Fatalf("files = %v", resp.File)
+	}
+	src := resp.File[0].GetContent()
+	for _, want := range []string{
+		`func (x *S2CLogin) MessageID() int32    { return 101 }`,
+		`func (x *C2SLogin) NodeName() string    { return "GAME" }`,
+		`func (x *C2SLogin) ModeName() string    { return "user" }`,
+		`protomessage.Register(`,
+		`func HandleC2SLogin(fn func(session.Session, *C2SLogin)) session.HandlerFunc {`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Fatalf("missing %q in\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateCollision(t *testing.T) {
+	resp := run(t,
+		testFile("a.proto", "", map[int]string{0: " @id 7\n"}, "A1", "A2"),
+		testFile("b.proto", "", map[int]string{0: " @id 8\n"}, "B1"),
+	)
+	if resp.Error == nil || !strings.Contains(*resp.Error, "message ID 8 used by both a.A2 and b.B1") {
+		t.Fatalf("error = %v", resp.Error)
+	}
+	resp = run(t, testFile("c.proto", "", nil, "C1"))
+	if resp.Error == nil || !strings.Contains(*resp.Error, "no @id") {
+		t.Fatalf("error = %v", resp.Error)
+	}
+}
@@ -0,0 +1,38 @@
+// Command protoc-gen-msgmeta generates the MessageID, MessageName, NodeName
+// and ModeName methods that make protobuf messages a
+// protomessage.ProtoMessage.
+//
+// Message IDs come from an "@id N" directive in the leading comment of a
+// message; messages without one take the previous message's ID plus one.
+// The node and model names come from the (route.server) file option, written
+// as "NODE.model", or from an "@route NODE.model" directive in the comment
+// above the package statement. IDs must be unique across every file of one
+// protoc invocation.
+//
+// Parameters:
+//
+//	registry=false  do not register the messages with protomessage.Register
+//	handlers=false  do not emit the typed Handle<Message> helpers
+//
+// Usage:
+//
+//	protoc --go_out=paths=source_relative:. --msgmeta_out=paths=source_relative:. user.proto
+package main
+
+import (
+	"flag"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func main() {
+	var flags flag.FlagSet
+	cfg := config{}
+	flags.BoolVar(&cfg.registry, "registry", true, "register messages with protomessage.Register")
+	flags.BoolVar(&cfg.handlers, "handlers", true, "emit typed handler helpers")
+	protogen.Options{ParamFunc: flags.Set}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		return generate(gen, cfg)
+	})
+}
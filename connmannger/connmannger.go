@@ -3,77 +3,212 @@ package connmannger
 import (
 	"errors"
 	"infra-foundation/session"
+	"iter"
 	"sync"
+	"sync/atomic"
 )
 
+const shardCount = 64
+
+type sessionShard struct {
+	m        sync.RWMutex
+	sessions map[int64]session.Session
+}
+
+type uidShard struct {
+	m    sync.RWMutex
+	uids map[int64]int64
+}
+
+// ConnManager indexes sessions by id, in shards so that lookups and updates
+// of different sessions do not contend. Iteration walks one shard at a time
+// over a snapshot of it, so the callbacks may store and remove sessions.
 type ConnManager struct {
-	idToSession  map[int64]session.Session
-	uidToSession map[int64]int64
-	m            sync.RWMutex
+	shards [shardCount]sessionShard
+	uids   [shardCount]uidShard
+	count  atomic.Int64
+	nuids  atomic.Int64
 }
 
 func NewConnManager() *ConnManager {
-	return &ConnManager{idToSession: map[int64]session.Session{}, uidToSession: map[int64]int64{}}
+	c := &ConnManager{}
+	for i := range shardCount {
+		c.shards[i].sessions = map[int64]session.Session{}
+		c.uids[i].uids = map[int64]int64{}
+	}
+	return c
+}
+
+func (c *ConnManager) shard(id int64) *sessionShard {
+	return &c.shards[uint64(id)%shardCount]
+}
+
+func (c *ConnManager) uidShard(uid int64) *uidShard {
+	return &c.uids[uint64(uid)%shardCount]
 }
 
 func (c *ConnManager) StoreSession(s session.Session) {
-	c.m.Lock()
-	c.idToSession[s.ID()] = s
-	c.uidToSession[s.UID()] = s.ID()
-	c.m.Unlock()
+	id, uid := s.ID(), s.UID()
+	sh := c.shard(id)
+	sh.m.Lock()
+	old, ok := sh.sessions[id]
+	sh.sessions[id] = s
+	sh.m.Unlock()
+	if !ok {
+		c.count.Add(1)
+	} else if old.UID() != uid {
+		c.unindexUID(old.UID(), id)
+	}
+	c.indexUID(uid, id)
 }
 
-func (c *ConnManager) Count() int { return len(c.idToSession) }
+func (c *ConnManager) indexUID(uid, id int64) {
+	if uid <= 0 {
+		return
+	}
+	us := c.uidShard(uid)
+	us.m.Lock()
+	if _, ok := us.uids[uid]; !ok {
+		c.nuids.Add(1)
+	}
+	us.uids[uid] = id
+	us.m.Unlock()
+}
 
+// unindexUID drops uid from the index when it still points at id.
+func (c *ConnManager) unindexUID(uid, id int64) {
+	if uid <= 0 {
+		return
+	}
+	us := c.uidShard(uid)
+	us.m.Lock()
+	if cur, ok := us.uids[uid]; ok && cur == id {
+		delete(us.uids, uid)
+		c.nuids.Add(-1)
+	}
+	us.m.Unlock()
+}
+
+// RebindUID moves s in the uid index after it changed its uid from old.
+func (c *ConnManager) RebindUID(s session.Session, old int64) {
+	if _, ok := c.GetByID(s.ID()); !ok || s.UID() == old {
+		return
+	}
+	c.unindexUID(old, s.ID())
+	c.indexUID(s.UID(), s.ID())
+}
+
+// Count returns the number of sessions.
+func (c *ConnManager) Count() int { return int(c.count.Load()) }
+
+// CountUIDs returns the number of bound uids indexed for GetByUID.
+func (c *ConnManager) CountUIDs() int { return int(c.nuids.Load()) }
+
+// GetByUID returns the session indexed for uid. Only StoreSession and
+// RebindUID keep the index, so a session changing its uid must report it
+// through RebindUID to be found.
 func (c *ConnManager) GetByUID(uid int64) (session.Session, bool) {
-	c.m.RLock()
-	defer c.m.RUnlock()
-	id, ok := c.uidToSession[uid]
+	us := c.uidShard(uid)
+	us.m.RLock()
+	id, ok := us.uids[uid]
+	us.m.RUnlock()
 	if !ok {
 		return nil, false
 	}
-	s, ok := c.idToSession[id]
-	return s, ok
+	if s, ok := c.GetByID(id); ok && s.UID() == uid {
+		return s, true
+	}
+	return nil, false
 }
 
 func (c *ConnManager) GetByID(id int64) (session.Session, bool) {
-	c.m.RLock()
-	defer c.m.RUnlock()
-	s, ok := c.idToSession[id]
+	sh := c.shard(id)
+	sh.m.RLock()
+	defer sh.m.RUnlock()
+	s, ok := sh.sessions[id]
 	return s, ok
 }
 
 func (c *ConnManager) RemoveByID(id int64) {
-	c.m.Lock()
-	defer c.m.Unlock()
-	s, ok := c.idToSession[id]
+	sh := c.shard(id)
+	sh.m.Lock()
+	s, ok := sh.sessions[id]
+	if ok {
+		delete(sh.sessions, id)
+	}
+	sh.m.Unlock()
 	if !ok {
 		return
 	}
-	delete(c.idToSession, id)
-	delete(c.uidToSession, s.UID())
+	c.count.Add(-1)
+	c.unindexUID(s.UID(), id)
 	session.DefaultConnSession.Remove(id)
 }
 
 func (c *ConnManager) RemoveByUID(uid int64) {
-	c.m.Lock()
-	defer c.m.Unlock()
-	id, ok := c.uidToSession[uid]
-	if !ok {
-		return
+	if s, ok := c.GetByUID(uid); ok {
+		c.RemoveByID(s.ID())
 	}
-	delete(c.idToSession, id)
-	delete(c.uidToSession, uid)
-	session.DefaultConnSession.Remove(id)
+}
+
+// All yields every session.
+func (c *ConnManager) All() iter.Seq[session.Session] {
+	return func(yield func(session.Session) bool) {
+		var snapshot []session.Session
+		for i := range c.shards {
+			sh := &c.shards[i]
+			sh.m.RLock()
+			snapshot = snapshot[:0]
+			for _, s := range sh.sessions {
+				snapshot = append(snapshot, s)
+			}
+			sh.m.RUnlock()
+			for _, s := range snapshot {
+				if !yield(s) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Filter yields the sessions keep reports true for.
+func (c *ConnManager) Filter(keep func(session.Session) bool) iter.Seq[session.Session] {
+	return func(yield func(session.Session) bool) {
+		for s := range c.All() {
+			if keep(s) && !yield(s) {
+				return
+			}
+		}
+	}
+}
+
+// ByUID yields the sessions bound to uid.
+func (c *ConnManager) ByUID(uid int64) iter.Seq[session.Session] {
+	return c.Filter(func(s session.Session) bool { return s.UID() == uid })
+}
+
+// ByServer yields the sessions bound to the node id for the service name,
+// or to any node of it when id is empty.
+func (c *ConnManager) ByServer(name, id string) iter.Seq[session.Session] {
+	return c.Filter(func(s session.Session) bool {
+		bound := s.GetServers(name)
+		return bound != "" && (id == "" || bound == id)
+	})
+}
+
+// ByAttr yields the sessions of c whose attribute attr is v.
+func ByAttr[T comparable](c *ConnManager, attr session.Attr[T], v T) iter.Seq[session.Session] {
+	return c.Filter(func(s session.Session) bool {
+		got, ok := attr.Get(s)
+		return ok && got == v
+	})
 }
 
 func (c *ConnManager) Range(cb func(s session.Session) error) error {
-	c.m.RLock()
-	idToSession := c.idToSession
-	c.m.RUnlock()
 	var errs []error
-	for _, conn := range idToSession {
-		errs = append(errs, cb(conn))
+	for s := range c.All() {
+		errs = append(errs, cb(s))
 	}
 	return errors.Join(errs...)
 }
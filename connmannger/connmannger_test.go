@@ -0,0 +1,105 @@
+package connmannger
+
+import (
+	"infra-foundation/protomessage"
+	"infra-foundation/session"
+	"iter"
+	"slices"
+	"sync"
+	"testing"
+)
+
+type testSession struct{ *session.NetworkEntities }
+
+func (testSession) Send(protomessage.ProtoMessage) error                      { return nil }
+func (testSession) Notify([]session.Session, protomessage.ProtoMessage) error { return nil }
+func (testSession) Close() error                                              { return nil }
+
+func newTestSession(id, uid int64) testSession {
+	return testSession{session.NewNetworkEntities(id, uid)}
+}
+
+func ids(seq iter.Seq[session.Session]) []int64 {
+	var out []int64
+	for s := range seq {
+		out = append(out, s.ID())
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestConnManagerViews(t *testing.T) {
+	c := NewConnManager()
+	room := session.NewAttr[string]("room")
+	for id := int64(1); id <= 6; id++ {
+		s := newTestSession(id, id%3)
+		if id%2 == 0 {
+			s.BindServers("game", "game-1")
+			room.Set(s, "lobby")
+		}
+		c.StoreSession(s)
+	}
+	if c.Count() != 6 || c.CountUIDs() != 2 {
+		t.Fatalf("counts = %d sessions %d uids", c.Count(), c.CountUIDs())
+	}
+	if got := ids(c.ByUID(1)); !slices.Equal(got, []int64{1, 4}) {
+		t.Fatalf("ByUID = %v", got)
+	}
+	if got := ids(c.ByServer("game", "")); !slices.Equal(got, []int64{2, 4, 6}) {
+		t.Fatalf("ByServer = %v", got)
+	}
+	if got := ids(ByAttr(c, room, "lobby")); !slices.Equal(got, []int64{2, 4, 6}) {
+		t.Fatalf("ByAttr = %v", got)
+	}
+
+	s, _ := c.GetByID(3)
+	s.BindUID(9)
+	c.RebindUID(s, 0)
+	if got, ok := c.GetByUID(9); !ok || got.ID() != 3 {
+		t.Fatalf("GetByUID after rebind = %v", got)
+	}
+	s, _ = c.GetByID(5)
+	s.BindUID(10)
+	if _, ok := c.GetByUID(10); ok {
+		t.Fatal("GetByUID found a uid bound without RebindUID")
+	}
+	c.RebindUID(s, 2)
+	if got, ok := c.GetByUID(10); !ok || got.ID() != 5 {
+		t.Fatalf("GetByUID after rebind = %v", got)
+	}
+	// removing while iterating
+	for s := range c.All() {
+		c.RemoveByID(s.ID())
+	}
+	if c.Count() != 0 || c.CountUIDs() != 0 || len(ids(c.All())) != 0 {
+		t.Fatalf("left %d sessions %d uids", c.Count(), c.CountUIDs())
+	}
+}
+
+func TestConnManagerConcurrent(t *testing.T) {
+	c := NewConnManager()
+	var wg sync.WaitGroup
+	for w := range int64(8) {
+		wg.Go(func() {
+			for i := range int64(500) {
+				id := w*1000 + i
+				c.StoreSession(newTestSession(id, id))
+				c.GetByUID(id)
+				if i%2 == 0 {
+					c.RemoveByID(id)
+				}
+			}
+		})
+		wg.Go(func() {
+			for range 20 {
+				for range c.All() {
+				}
+				_ = c.Count()
+			}
+		})
+	}
+	wg.Wait()
+	if c.Count() != 8*250 || len(ids(c.All())) != 8*250 || c.CountUIDs() != 8*250 {
+		t.Fatalf("count = %d uids %d", c.Count(), c.CountUIDs())
+	}
+}
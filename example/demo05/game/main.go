@@ -70,7 +70,9 @@ func main() {
 
 	logx.Dbg.Println(model.HandlersRoutes())
 
-	discovery.RegisterService(os.Args[1], fmt.Sprintf("%s:%s", localAddr, strings.Split(os.Args[2], ":")[1]), false, model.HandlersRoutes())
+	if err := discovery.RegisterService(os.Args[1], fmt.Sprintf("%s:%s", localAddr, strings.Split(os.Args[2], ":")[1]), false, model.HandlersRoutes()); err != nil {
+		panic(err)
+	}
 
 	s := cluster.NewServer()
 	if err := s.Listen(os.Args[2]); err != nil {
@@ -62,7 +62,9 @@ func main() {
 	}
 	localAddr = "192.168.110.67"
 
-	discovery.RegisterService(os.Args[1], fmt.Sprintf("%s:%s", localAddr, strings.Split(os.Args[2], ":")[1]), true, model.HandlersRoutes())
+	if err := discovery.RegisterService(os.Args[1], fmt.Sprintf("%s:%s", localAddr, strings.Split(os.Args[2], ":")[1]), true, model.HandlersRoutes()); err != nil {
+		panic(err)
+	}
 
 	s := cluster.NewServer()
 	if err := s.Listen(os.Args[2]); err != nil {
@@ -0,0 +1,3 @@
+package protos
+
+//go:generate protoc --go_out=paths=source_relative:. --msgmeta_out=paths=source_relative:. route.proto user.proto
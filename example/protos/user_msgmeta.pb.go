@@ -1,5 +1,13 @@
+// Code generated by protoc-gen-msgmeta. DO NOT EDIT.
+// source: user.proto
+
 package protos
 
+import (
+	protomessage "infra-foundation/protomessage"
+	session "infra-foundation/session"
+)
+
 func (x *C2SLogin) MessageID() int32    { return 100 }
 func (x *C2SLogin) MessageName() string { return "C2SLogin" }
 func (x *C2SLogin) NodeName() string    { return "GAME" }
@@ -16,3 +24,52 @@ func (x *N2MLogin) MessageID() int32    { return 103 }
 func (x *N2MLogin) MessageName() string { return "N2MLogin" }
 func (x *N2MLogin) NodeName() string    { return "GAME" }
 func (x *N2MLogin) ModeName() string    { return "user" }
+
+func init() {
+	protomessage.Register(
+		&C2SLogin{},
+		&S2CLogin{},
+		&M2NLogin{},
+		&N2MLogin{},
+	)
+}
+
+func HandleC2SLogin(fn func(session.Session, *C2SLogin)) session.HandlerFunc {
+	return func(s session.Session, pb protomessage.ProtoMessage) { fn(s, pb.(*C2SLogin)) }
+}
+
+func ReplyC2SLogin(fn func(session.Session, *C2SLogin) (protomessage.ProtoMessage, error)) session.ReplyHandlerFunc {
+	return func(s session.Session, pb protomessage.ProtoMessage) (protomessage.ProtoMessage, error) {
+		return fn(s, pb.(*C2SLogin))
+	}
+}
+
+func HandleS2CLogin(fn func(session.Session, *S2CLogin)) session.HandlerFunc {
+	return func(s session.Session, pb protomessage.ProtoMessage) { fn(s, pb.(*S2CLogin)) }
+}
+
+func ReplyS2CLogin(fn func(session.Session, *S2CLogin) (protomessage.ProtoMessage, error)) session.ReplyHandlerFunc {
+	return func(s session.Session, pb protomessage.ProtoMessage) (protomessage.ProtoMessage, error) {
+		return fn(s, pb.(*S2CLogin))
+	}
+}
+
+func HandleM2NLogin(fn func(session.Session, *M2NLogin)) session.HandlerFunc {
+	return func(s session.Session, pb protomessage.ProtoMessage) { fn(s, pb.(*M2NLogin)) }
+}
+
+func ReplyM2NLogin(fn func(session.Session, *M2NLogin) (protomessage.ProtoMessage, error)) session.ReplyHandlerFunc {
+	return func(s session.Session, pb protomessage.ProtoMessage) (protomessage.ProtoMessage, error) {
+		return fn(s, pb.(*M2NLogin))
+	}
+}
+
+func HandleN2MLogin(fn func(session.Session, *N2MLogin)) session.HandlerFunc {
+	return func(s session.Session, pb protomessage.ProtoMessage) { fn(s, pb.(*N2MLogin)) }
+}
+
+func ReplyN2MLogin(fn func(session.Session, *N2MLogin) (protomessage.ProtoMessage, error)) session.ReplyHandlerFunc {
+	return func(s session.Session, pb protomessage.ProtoMessage) (protomessage.ProtoMessage, error) {
+		return fn(s, pb.(*N2MLogin))
+	}
+}
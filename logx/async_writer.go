@@ -0,0 +1,170 @@
+package logx
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+type FullPolicy int8
+
+const (
+	DropWhenFull FullPolicy = iota
+	BlockWhenFull
+)
+
+const defaultAsyncCapacity = 8192
+
+var ErrAsyncWriterClosed = errors.New("logx: async writer closed")
+
+type flusher interface {
+	Flush() error
+}
+
+type AsyncWriter struct {
+	w        io.Writer
+	policy   FullPolicy
+	ring     [][]byte
+	head     int
+	count    int
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	drained  *sync.Cond
+	pushed   uint64
+	written  uint64
+	closed   bool
+	dropped  atomic.Uint64
+	failed   atomic.Uint64
+	err      error
+	wg       sync.WaitGroup
+}
+
+func NewAsyncWriter(w io.Writer, capacity int, policy FullPolicy) *AsyncWriter {
+	if capacity <= 0 {
+		capacity = defaultAsyncCapacity
+	}
+	a := &AsyncWriter{w: w, policy: policy, ring: make([][]byte, capacity)}
+	a.notEmpty = sync.NewCond(&a.mu)
+	a.notFull = sync.NewCond(&a.mu)
+	a.drained = sync.NewCond(&a.mu)
+	a.wg.Go(a.writeLoop)
+	return a
+}
+
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return 0, ErrAsyncWriterClosed
+	}
+	for a.count == len(a.ring) {
+		if a.policy == DropWhenFull {
+			a.dropped.Add(1)
+			return len(p), nil
+		}
+		a.notFull.Wait()
+		if a.closed {
+			return 0, ErrAsyncWriterClosed
+		}
+	}
+	a.ring[(a.head+a.count)%len(a.ring)] = append([]byte(nil), p...)
+	a.count++
+	a.pushed++
+	a.notEmpty.Signal()
+	return len(p), nil
+}
+
+func (a *AsyncWriter) Dropped() uint64 { return a.dropped.Load() }
+
+// Failed reports how many buffered writes the underlying writer rejected.
+func (a *AsyncWriter) Failed() uint64 { return a.failed.Load() }
+
+// Flush waits for everything written so far to reach the underlying writer
+// and returns the first write error seen since the previous Flush.
+func (a *AsyncWriter) Flush() error {
+	a.mu.Lock()
+	target := a.pushed
+	for a.written < target && !(a.closed && a.count == 0) {
+		a.drained.Wait()
+	}
+	err := a.takeErr()
+	a.mu.Unlock()
+	if f, ok := a.w.(flusher); ok {
+		return errors.Join(err, f.Flush())
+	}
+	return err
+}
+
+func (a *AsyncWriter) takeErr() error {
+	err := a.err
+	a.err = nil
+	return err
+}
+
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.notEmpty.Broadcast()
+	a.notFull.Broadcast()
+	a.mu.Unlock()
+	a.wg.Wait()
+
+	a.mu.Lock()
+	errs := []error{a.takeErr()}
+	a.mu.Unlock()
+	if f, ok := a.w.(flusher); ok {
+		errs = append(errs, f.Flush())
+	}
+	if c, ok := a.w.(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (a *AsyncWriter) writeLoop() {
+	batch := make([][]byte, 0, 64)
+	for {
+		a.mu.Lock()
+		for a.count == 0 && !a.closed {
+			a.notEmpty.Wait()
+		}
+		if a.count == 0 && a.closed {
+			a.drained.Broadcast()
+			a.mu.Unlock()
+			return
+		}
+		batch = batch[:0]
+		for a.count > 0 && len(batch) < cap(batch) {
+			batch = append(batch, a.ring[a.head])
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+			a.count--
+		}
+		a.notFull.Broadcast()
+		a.mu.Unlock()
+
+		var werr error
+		for _, b := range batch {
+			if _, err := a.w.Write(b); err != nil {
+				a.failed.Add(1)
+				if werr == nil {
+					werr = err
+				}
+			}
+		}
+
+		a.mu.Lock()
+		if a.err == nil {
+			a.err = werr
+		}
+		a.written += uint64(len(batch))
+		a.drained.Broadcast()
+		a.mu.Unlock()
+	}
+}
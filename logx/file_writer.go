@@ -0,0 +1,293 @@
+package logx
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "20060102T150405.000"
+
+// renameFile renames the log file on rotation; tests replace it to fail.
+var renameFile = os.Rename
+
+type FileConfig struct {
+	Filename       string
+	MaxSize        int64
+	RotateInterval time.Duration
+	MaxBackups     int
+	Compress       bool
+}
+
+type FileWriter struct {
+	cfg      FileConfig
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	rotateAt time.Time
+
+	// pending backups are compressed and pruned by a single background
+	// worker so a prune never races with an in-flight compression.
+	bgMu    sync.Mutex
+	pending []string
+	working bool
+	wg      sync.WaitGroup
+}
+
+func NewFileWriter(cfg FileConfig) (*FileWriter, error) {
+	if cfg.Filename == "" {
+		return nil, errors.New("[FileWriter] Filename is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Filename), 0o755); err != nil {
+		return nil, fmt.Errorf("[FileWriter] MkdirAll %w", err)
+	}
+	f := &FileWriter{cfg: cfg}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	var rerr error
+	if f.shouldRotate(int64(len(p))) {
+		rerr = f.rotate()
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err == nil {
+		err = rerr
+	}
+	return n, err
+}
+
+func (f *FileWriter) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+func (f *FileWriter) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+	f.wg.Wait()
+	return err
+}
+
+func (f *FileWriter) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+func (f *FileWriter) shouldRotate(n int64) bool {
+	if f.cfg.MaxSize > 0 && f.size > 0 && f.size+n > f.cfg.MaxSize {
+		return true
+	}
+	return !f.rotateAt.IsZero() && !time.Now().Before(f.rotateAt)
+}
+
+func (f *FileWriter) open() error {
+	file, err := os.OpenFile(f.cfg.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("[FileWriter/open] %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("[FileWriter/open] Stat %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	if f.cfg.RotateInterval > 0 {
+		f.rotateAt = time.Now().Truncate(f.cfg.RotateInterval).Add(f.cfg.RotateInterval)
+	}
+	return nil
+}
+
+// rotate moves the log file aside and opens a new one. The old file stays
+// open until the new one is, so a failed rotation keeps logging to it and is
+// retried on a later write.
+func (f *FileWriter) rotate() error {
+	backup := f.backupName(time.Now())
+	if err := renameFile(f.cfg.Filename, backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("[FileWriter/rotate] Rename %w", err)
+	}
+	old := f.file
+	if err := f.open(); err != nil {
+		return err
+	}
+	if err := old.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "[FileWriter/rotate] Close %v\n", err)
+	}
+	f.schedule(backup)
+	return nil
+}
+
+func (f *FileWriter) schedule(backup string) {
+	f.bgMu.Lock()
+	f.pending = append(f.pending, backup)
+	if f.working {
+		f.bgMu.Unlock()
+		return
+	}
+	f.working = true
+	f.bgMu.Unlock()
+	f.wg.Go(f.maintain)
+}
+
+func (f *FileWriter) maintain() {
+	for {
+		f.bgMu.Lock()
+		batch := f.pending
+		f.pending = nil
+		if len(batch) == 0 {
+			f.working = false
+			f.bgMu.Unlock()
+			return
+		}
+		f.bgMu.Unlock()
+
+		if f.cfg.Compress {
+			for _, backup := range batch {
+				if err := compressFile(backup); err != nil && !os.IsNotExist(err) {
+					fmt.Fprintf(os.Stderr, "[FileWriter/maintain] compress %s: %v\n", backup, err)
+				}
+			}
+		}
+		f.prune()
+	}
+}
+
+// backupName returns a backup path for t that does not collide with an
+// existing backup; rotations within the same millisecond get a -N suffix.
+func (f *FileWriter) backupName(t time.Time) string {
+	dir := filepath.Dir(f.cfg.Filename)
+	ext := filepath.Ext(f.cfg.Filename)
+	base := filepath.Join(dir, strings.TrimSuffix(filepath.Base(f.cfg.Filename), ext)+"-"+t.Format(backupTimeFormat))
+	for seq := 0; ; seq++ {
+		name := base + ext
+		if seq > 0 {
+			name = base + "-" + strconv.Itoa(seq) + ext
+		}
+		if !fileExists(name) && !fileExists(name+".gz") {
+			return name
+		}
+	}
+}
+
+type backupFile struct {
+	name string
+	at   time.Time
+	seq  int
+}
+
+func (f *FileWriter) backups() []string {
+	dir := filepath.Dir(f.cfg.Filename)
+	ext := filepath.Ext(f.cfg.Filename)
+	prefix := strings.TrimSuffix(filepath.Base(f.cfg.Filename), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		seq := 0
+		if i := strings.LastIndexByte(ts, '-'); i >= 0 {
+			if seq, err = strconv.Atoi(ts[i+1:]); err != nil || seq <= 0 {
+				continue
+			}
+			ts = ts[:i]
+		}
+		at, err := time.Parse(backupTimeFormat, ts)
+		if err != nil {
+			continue
+		}
+		files = append(files, backupFile{name: filepath.Join(dir, name), at: at, seq: seq})
+	}
+	slices.SortFunc(files, func(a, b backupFile) int {
+		if c := a.at.Compare(b.at); c != 0 {
+			return c
+		}
+		return a.seq - b.seq
+	})
+	names := make([]string, len(files))
+	for i, b := range files {
+		names[i] = b.name
+	}
+	return names
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func (f *FileWriter) prune() {
+	if f.cfg.MaxBackups <= 0 {
+		return
+	}
+	names := f.backups()
+	if len(names) <= f.cfg.MaxBackups {
+		return
+	}
+	for _, name := range names[:len(names)-f.cfg.MaxBackups] {
+		_ = os.Remove(name)
+	}
+}
+
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
@@ -0,0 +1,167 @@
+package logx
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileWriterRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewFileWriter(FileConfig{Filename: filepath.Join(dir, "app.log"), MaxSize: 64, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := []byte(strings.Repeat("x", 40) + "\n")
+	for range 5 {
+		if _, err = w.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(w.backups()); n != 2 {
+		t.Fatalf("backups = %d, want 2", n)
+	}
+	info, err := os.Stat(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(line)) {
+		t.Fatalf("size = %d, want %d", info.Size(), len(line))
+	}
+}
+
+func TestFileWriterCompress(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewFileWriter(FileConfig{Filename: filepath.Join(dir, "app.log"), Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("hello\n"))
+	if err = w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	matches, _ := filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+	if len(matches) != 1 {
+		t.Fatalf("gz backups = %v", matches)
+	}
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+func TestAsyncWriterFlush(t *testing.T) {
+	var sb syncBuffer
+	a := NewAsyncWriter(&sb, 4, BlockWhenFull)
+	for range 100 {
+		a.Write([]byte("a"))
+	}
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(sb.String()); n != 100 {
+		t.Fatalf("written = %d, want 100", n)
+	}
+	a.Close()
+	if _, err := a.Write([]byte("a")); err != ErrAsyncWriterClosed {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestFileWriterBackupNameCollision(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewFileWriter(FileConfig{Filename: filepath.Join(dir, "app.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	now := time.Now()
+	var names []string
+	for range 3 {
+		name := w.backupName(now)
+		if err = os.WriteFile(name, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if got := w.backups(); !slices.Equal(got, names) {
+		t.Fatalf("backups = %v, want %v", got, names)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestAsyncWriterReportsWriteErrors(t *testing.T) {
+	a := NewAsyncWriter(failWriter{}, 4, BlockWhenFull)
+	a.Write([]byte("a"))
+	a.Write([]byte("b"))
+	if err := a.Flush(); err == nil || err.Error() != "disk full" {
+		t.Fatalf("Flush err = %v", err)
+	}
+	if n := a.Failed(); n != 2 {
+		t.Fatalf("failed = %d, want 2", n)
+	}
+	if err := a.Flush(); err != nil {
+		t.Fatalf("second Flush err = %v", err)
+	}
+	a.Close()
+}
+
+func TestFileWriterRotateFailureKeepsLogging(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	w, err := NewFileWriter(FileConfig{Filename: name, MaxSize: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	denied := errors.New("permission denied")
+	renameFile = func(string, string) error { return denied }
+	defer func() { renameFile = os.Rename }()
+
+	line := []byte(strings.Repeat("x", 40) + "\n")
+	for range 3 {
+		if n, err := w.Write(line); n != len(line) {
+			t.Fatalf("n = %d err = %v", n, err)
+		}
+	}
+	if _, err = w.Write(line); !errors.Is(err, denied) {
+		t.Fatalf("rotation error not reported: %v", err)
+	}
+	if info, _ := os.Stat(name); info.Size() != 4*int64(len(line)) {
+		t.Fatalf("size = %d", info.Size())
+	}
+
+	renameFile = os.Rename
+	if _, err = w.Write(line); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(name); info.Size() != int64(len(line)) {
+		t.Fatalf("size after rotation = %d", info.Size())
+	}
+}
@@ -3,6 +3,7 @@ package logx
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime"
@@ -14,10 +15,32 @@ import (
 
 var _BufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
 
+var (
+	outputMu sync.Mutex
+	out      io.Writer = os.Stderr
+)
+
 func InitLogger() {
 	log.SetFlags(log.LstdFlags)
 }
 
+func SetOutput(w io.Writer) {
+	outputMu.Lock()
+	out = w
+	log.SetOutput(w)
+	outputMu.Unlock()
+}
+
+func Flush() error {
+	outputMu.Lock()
+	w := out
+	outputMu.Unlock()
+	if f, ok := w.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
 func formatPrefix(b *bytes.Buffer, level level, file string, line int) {
 	b.WriteString(level.String())
 	b.WriteByte(' ')
@@ -85,11 +108,13 @@ type FatalLevel struct{ level level }
 
 func (f FatalLevel) Fatal(v ...any) {
 	output(f.level, v...)
+	Flush()
 	os.Exit(1)
 }
 
 func (f FatalLevel) Fatalf(format string, v ...any) {
 	outputf(f.level, format, v...)
+	Flush()
 	os.Exit(1)
 }
 
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	protomessage "infra-foundation/protomessage"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	ErrModelNotFound   = errors.New("model: model not found")
+	ErrAskNotSupported = errors.New("model: ask handler not found")
+)
+
+// Remote forwards an ask to the node hosting the named model. The cluster
+// package installs one with SetRemote.
+type Remote interface {
+	Ask(ctx context.Context, name string, req protomessage.ProtoMessage) (protomessage.ProtoMessage, error)
+}
+
+type AskFunc func(ctx context.Context, req protomessage.ProtoMessage) (protomessage.ProtoMessage, error)
+
+type askKey struct {
+	model string
+	typ   protoreflect.FullName
+}
+
+var (
+	askHandlers sync.Map
+	remote      Remote
+)
+
+func SetRemote(r Remote) {
+	remote = r
+}
+
+// RegisterAsk installs fn as the handler of Req asks sent to the model name.
+// fn runs on the model's mailbox.
+func RegisterAsk[Req, Resp protomessage.ProtoMessage](name string, fn func(context.Context, Req) (Resp, error)) {
+	var req Req
+	askHandlers.Store(askKey{name, req.ProtoReflect().Descriptor().FullName()}, AskFunc(func(ctx context.Context, pb protomessage.ProtoMessage) (protomessage.ProtoMessage, error) {
+		r, ok := pb.(Req)
+		if !ok {
+			return nil, fmt.Errorf("[model/Ask] %s got %T", name, pb)
+		}
+		return fn(ctx, r)
+	}))
+}
+
+// Ask sends req to the model name and waits for its reply. The model runs
+// locally when registered in DefaultModelManager, otherwise the ask is routed
+// to a node hosting it; ctx cancellation and deadline reach the remote side.
+func Ask[Req, Resp protomessage.ProtoMessage](ctx context.Context, name string, req Req) (Resp, error) {
+	var zero Resp
+	resp, err := DefaultModelManager.Ask(ctx, name, req)
+	if errors.Is(err, ErrModelNotFound) && remote != nil {
+		resp, err = remote.Ask(ctx, name, req)
+	}
+	if err != nil {
+		return zero, err
+	}
+	if resp == nil {
+		return zero, nil
+	}
+	r, ok := resp.(Resp)
+	if !ok {
+		return zero, fmt.Errorf("[model/Ask] %s replied %T", name, resp)
+	}
+	return r, nil
+}
+
+func (m *ModelManager) Ask(ctx context.Context, name string, req protomessage.ProtoMessage) (protomessage.ProtoMessage, error) {
+	resultChan := make(chan result[protomessage.ProtoMessage], 1)
+	err := m.AskAsync(ctx, name, req, func(resp protomessage.ProtoMessage, err error) {
+		resultChan <- result[protomessage.ProtoMessage]{resp, err}
+	})
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case res := <-resultChan:
+		return res.Result, res.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// AskAsync runs the ask handler for req on the mailbox of the local model name,
+// on the shard chosen by its AskKeyer if it has one, and hands the reply to cb. Asks whose ctx is done before they are dequeued
+// are answered with ctx.Err() without running the handler.
+func (m *ModelManager) AskAsync(ctx context.Context, name string, req protomessage.ProtoMessage, cb func(protomessage.ProtoMessage, error)) error {
+	md, ok := m.GetModel(name)
+	if !ok {
+		return fmt.Errorf("[ModelManager/Ask] %s %w", name, ErrModelNotFound)
+	}
+	v, ok := askHandlers.Load(askKey{name, req.ProtoReflect().Descriptor().FullName()})
+	if !ok {
+		return fmt.Errorf("[ModelManager/Ask] %s %s %w", name, req.ProtoReflect().Descriptor().FullName(), ErrAskNotSupported)
+	}
+	fn := v.(AskFunc)
+	var key uint64
+	if md.asker != nil {
+		key = md.asker.AskShardKey(req)
+	}
+	return md.PostKey(key, func() {
+		if err := ctx.Err(); err != nil {
+			cb(nil, err)
+			return
+		}
+		cb(fn(ctx, req))
+	})
+}
+
+func (m *ModelManager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.order...)
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"infra-foundation/example/protos"
+	protomessage "infra-foundation/protomessage"
+	"infra-foundation/session"
+	"testing"
+	"time"
+)
+
+type askModel struct{}
+
+func (askModel) Name() string                    { return "ask" }
+func (askModel) OnInit() error                   { return nil }
+func (askModel) OnStart() error                  { return nil }
+func (askModel) OnStop() error                   { return nil }
+func (askModel) OnDisconnection(session.Session) {}
+
+func TestAskLocal(t *testing.T) {
+	if err := Register(askModel{}); err != nil {
+		t.Fatal(err)
+	}
+	defer DefaultModelManager.Unregister("ask")
+	RegisterAsk("ask", func(ctx context.Context, req *protos.C2SLogin) (*protos.S2CLogin, error) {
+		return &protos.S2CLogin{Name: req.Name + "!"}, nil
+	})
+	resp, err := Ask[*protos.C2SLogin, *protos.S2CLogin](context.Background(), "ask", &protos.C2SLogin{Name: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Name != "hi!" {
+		t.Fatalf("resp = %q", resp.Name)
+	}
+
+	if _, err = Ask[*protos.C2SLogin, *protos.S2CLogin](context.Background(), "missing", &protos.C2SLogin{}); !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("err = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	md, _ := DefaultModelManager.GetModel("ask")
+	md.PostFunc(func() { time.Sleep(10 * time.Millisecond) })
+	if _, err = Ask[*protos.C2SLogin, *protos.S2CLogin](ctx, "ask", &protos.C2SLogin{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+type keyedAskModel struct{ shardedModel }
+
+func (keyedAskModel) Name() string { return "keyedAsk" }
+
+// ShardKey dereferences the session, as user keyers usually do.
+func (keyedAskModel) ShardKey(s session.Session, _ protomessage.ProtoMessage) uint64 {
+	return uint64(s.UID())
+}
+
+func (keyedAskModel) AskShardKey(req protomessage.ProtoMessage) uint64 {
+	return uint64(len(req.(*protos.C2SLogin).Name))
+}
+
+func TestAskShardKey(t *testing.T) {
+	mm := NewModelManager()
+	defer mm.Stop()
+	if err := mm.Register(&keyedAskModel{shardedModel{shards: 4}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mm.Start(); err != nil {
+		t.Fatal(err)
+	}
+	RegisterAsk("keyedAsk", func(ctx context.Context, req *protos.C2SLogin) (*protos.S2CLogin, error) {
+		return &protos.S2CLogin{Name: req.Name}, nil
+	})
+	md, _ := mm.GetModel("keyedAsk")
+	for _, name := range []string{"a", "bb", "ccc"} {
+		// Hold the keyed shard: the ask must wait behind it.
+		release := make(chan struct{})
+		md.PostFuncKey(uint64(len(name)), func() { <-release })
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := mm.Ask(ctx, "keyedAsk", &protos.C2SLogin{Name: name})
+		cancel()
+		close(release)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("ask %q did not wait for its keyed shard: %v", name, err)
+		}
+		resp, err := mm.Ask(context.Background(), "keyedAsk", &protos.C2SLogin{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.(*protos.S2CLogin).Name != name {
+			t.Fatalf("resp = %v", resp)
+		}
+	}
+}
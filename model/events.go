@@ -0,0 +1,75 @@
+package model
+
+import (
+	"infra-foundation/session"
+	"maps"
+)
+
+// Optional session event hooks. Each is delivered on the mailbox shard of the
+// session, which stays the same for its lifetime, so the events of one session
+// and its messages run in order. Gate nodes deliver them for client
+// connections from the moment they are accepted, backend nodes for the
+// sessions routed to them.
+
+type ConnectionHandler interface {
+	OnConnection(session.Session)
+}
+
+type BindUIDHandler interface {
+	OnBindUID(s session.Session, uid int64)
+}
+
+// BindServerHandler is told which node of each server name a session is bound
+// to whenever the binding changes.
+type BindServerHandler interface {
+	OnBindServer(s session.Session, servers map[string]string)
+}
+
+type SessionCloseHandler interface {
+	OnSessionClose(session.Session)
+}
+
+func (m *ModelManager) OnConnection(s session.Session) {
+	m.each(s, func(md Model) {
+		if h, ok := md.(ConnectionHandler); ok {
+			h.OnConnection(s)
+		}
+	})
+}
+
+func (m *ModelManager) OnBindUID(s session.Session, uid int64) {
+	m.each(s, func(md Model) {
+		if h, ok := md.(BindUIDHandler); ok {
+			h.OnBindUID(s, uid)
+		}
+	})
+}
+
+func (m *ModelManager) OnBindServer(s session.Session, servers map[string]string) {
+	servers = maps.Clone(servers)
+	m.each(s, func(md Model) {
+		if h, ok := md.(BindServerHandler); ok {
+			h.OnBindServer(s, servers)
+		}
+	})
+}
+
+func (m *ModelManager) OnSessionClose(s session.Session) {
+	m.each(s, func(md Model) {
+		if h, ok := md.(SessionCloseHandler); ok {
+			h.OnSessionClose(s)
+		}
+	})
+}
+
+func (m *ModelManager) each(s session.Session, f func(Model)) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, name := range m.order {
+		md, ok := m.modes[name]
+		if !ok {
+			continue
+		}
+		md.shard(md.sessionKey(s, nil)).PushTask(func() { f(md.Model) })
+	}
+}
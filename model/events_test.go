@@ -0,0 +1,54 @@
+package model
+
+import (
+	"context"
+	protomessage "infra-foundation/protomessage"
+	"infra-foundation/session"
+	"slices"
+	"testing"
+)
+
+type eventModel struct{ events []string }
+
+func (e *eventModel) Name() string                    { return "events" }
+func (e *eventModel) OnInit() error                   { return nil }
+func (e *eventModel) OnStart() error                  { return nil }
+func (e *eventModel) OnStop() error                   { return nil }
+func (e *eventModel) OnDisconnection(session.Session) { e.events = append(e.events, "disconnection") }
+func (e *eventModel) OnConnection(session.Session)    { e.events = append(e.events, "connection") }
+func (e *eventModel) OnBindUID(_ session.Session, uid int64) {
+	e.events = append(e.events, "uid")
+}
+func (e *eventModel) OnBindServer(_ session.Session, servers map[string]string) {
+	e.events = append(e.events, "server "+servers["GAME"])
+}
+func (e *eventModel) OnSessionClose(session.Session) { e.events = append(e.events, "close") }
+
+type testSession struct{ *session.NetworkEntities }
+
+func (testSession) Send(protomessage.ProtoMessage) error                      { return nil }
+func (testSession) Notify([]session.Session, protomessage.ProtoMessage) error { return nil }
+func (testSession) Close() error                                              { return nil }
+
+func TestSessionEvents(t *testing.T) {
+	mm := NewModelManager()
+	defer mm.Stop()
+	em := &eventModel{}
+	mm.Register(em)
+	mm.Register(&shardedModel{shards: 1})
+
+	s := testSession{session.NewNetworkEntities(1, -1)}
+	mm.OnConnection(s)
+	mm.OnBindUID(s, 7)
+	s.BindServers("GAME", "2")
+	mm.OnBindServer(s, s.Servers())
+	mm.OnDisconnection(s)
+	mm.OnSessionClose(s)
+
+	md, _ := mm.GetModel("events")
+	Do(context.Background(), md, func() (struct{}, error) { return struct{}{}, nil })
+	want := []string{"connection", "uid", "server 2", "disconnection", "close"}
+	if !slices.Equal(em.events, want) {
+		t.Fatalf("events = %v", em.events)
+	}
+}
@@ -0,0 +1,206 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrModelNotStarted = errors.New("model: model not started")
+	ErrModelStopped    = errors.New("model: model stopped")
+)
+
+type State int32
+
+const (
+	StateRegistered State = iota
+	StateInitialized
+	StateStarted
+	StateStopping
+	StateStopped
+)
+
+func (s State) String() string {
+	switch s {
+	case StateRegistered:
+		return "Registered"
+	case StateInitialized:
+		return "Initialized"
+	case StateStarted:
+		return "Started"
+	case StateStopping:
+		return "Stopping"
+	case StateStopped:
+		return "Stopped"
+	}
+	return fmt.Sprintf("State(%d)", int32(s))
+}
+
+// Dependent is implemented by models that must be initialized and started
+// after, and stopped before, the models they name.
+type Dependent interface {
+	Dependencies() []string
+}
+
+func dependencies(m Model) []string {
+	if d, ok := m.(Dependent); ok {
+		return d.Dependencies()
+	}
+	return nil
+}
+
+// sortModels orders names so that every model comes after its dependencies,
+// keeping registration order otherwise. Dependencies outside modes must be
+// present in started.
+func sortModels(names []string, modes map[string]*model, started func(string) bool) ([]string, error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	marks := make(map[string]int, len(names))
+	sorted := make([]string, 0, len(names))
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch marks[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle %s -> %s", strings.Join(path, " -> "), name)
+		}
+		marks[name] = visiting
+		path = append(path, name)
+		for _, dep := range dependencies(modes[name].Model) {
+			if _, ok := modes[dep]; !ok {
+				if started(dep) {
+					continue
+				}
+				return fmt.Errorf("%q depends on %q which is not registered", name, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		marks[name] = visited
+		sorted = append(sorted, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// Start initializes and then starts every registered model in dependency
+// order. If a model fails to start, the models already started by this call
+// are stopped again in reverse order and the error is returned; Start may be
+// retried once the cause is fixed. Models registered after Start are started
+// by Register. Stop closes the mailboxes for good, so Start after Stop fails
+// with ErrModelStopped and starts nothing.
+func (m *ModelManager) Start() error {
+	m.lifecycle.Lock()
+	defer m.lifecycle.Unlock()
+
+	m.mu.RLock()
+	pending := map[string]*model{}
+	var names []string
+	for _, name := range m.order {
+		if md := m.modes[name]; md.State() != StateStarted && md.State() != StateStopping {
+			if md.closed.Load() {
+				m.mu.RUnlock()
+				return fmt.Errorf("model.Manager.Start: %s %w", name, ErrModelStopped)
+			}
+			pending[name] = md
+			names = append(names, name)
+		}
+	}
+	m.mu.RUnlock()
+
+	if err := m.startModels(names, pending); err != nil {
+		return fmt.Errorf("model.Manager.Start: %w", err)
+	}
+	m.started = true
+	return nil
+}
+
+func (m *ModelManager) startModels(names []string, pending map[string]*model) error {
+	sorted, err := sortModels(names, pending, m.isStarted)
+	if err != nil {
+		return err
+	}
+	for _, name := range sorted {
+		md := pending[name]
+		if md.State() != StateRegistered {
+			continue
+		}
+		if err := md.Model.OnInit(); err != nil {
+			return fmt.Errorf("%s OnInit %w", name, err)
+		}
+		md.setState(StateInitialized)
+	}
+	for i, name := range sorted {
+		md := pending[name]
+		if err := md.Model.OnStart(); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				pending[sorted[j]].stopModel()
+			}
+			return fmt.Errorf("%s OnStart %w", name, err)
+		}
+		md.setState(StateStarted)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rank := make(map[string]int, len(sorted))
+	for i, name := range sorted {
+		rank[name] = i
+	}
+	// Keep m.order topologically sorted so Stop can walk it backwards.
+	var order []string
+	for _, name := range m.order {
+		if _, ok := rank[name]; !ok {
+			order = append(order, name)
+		}
+	}
+	m.order = append(order, sorted...)
+	return nil
+}
+
+func (m *ModelManager) startOne(name string, md *model) error {
+	return m.startModels([]string{name}, map[string]*model{name: md})
+}
+
+func (m *ModelManager) isStarted(name string) bool {
+	md, ok := m.GetModel(name)
+	return ok && md.State() == StateStarted
+}
+
+// State reports the lifecycle state of the model name.
+func (m *ModelManager) State(name string) (State, bool) {
+	md, ok := m.GetModel(name)
+	if !ok {
+		return 0, false
+	}
+	return md.State(), true
+}
+
+func (m *model) State() State {
+	return State(m.state.Load())
+}
+
+func (m *model) setState(s State) {
+	m.state.Store(int32(s))
+}
+
+// stopModel runs OnStop for a started model, leaving its mailbox running.
+func (m *model) stopModel() {
+	if !m.state.CompareAndSwap(int32(StateStarted), int32(StateStopping)) {
+		return
+	}
+	m.Model.OnStop()
+	m.setState(StateStopped)
+}
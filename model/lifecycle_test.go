@@ -0,0 +1,147 @@
+package model
+
+import (
+	"errors"
+	"infra-foundation/example/protos"
+	protomessage "infra-foundation/protomessage"
+	"infra-foundation/session"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type depModel struct {
+	name     string
+	deps     []string
+	startErr error
+	events   *[]string
+}
+
+func (d *depModel) Name() string                    { return d.name }
+func (d *depModel) Dependencies() []string          { return d.deps }
+func (d *depModel) OnInit() error                   { *d.events = append(*d.events, "init "+d.name); return nil }
+func (d *depModel) OnStop() error                   { *d.events = append(*d.events, "stop "+d.name); return nil }
+func (d *depModel) OnDisconnection(session.Session) {}
+
+func (d *depModel) OnStart() error {
+	if d.startErr != nil {
+		return d.startErr
+	}
+	*d.events = append(*d.events, "start "+d.name)
+	return nil
+}
+
+func TestModelDependencyOrder(t *testing.T) {
+	var events []string
+	mm := NewModelManager()
+	mm.Register(&depModel{name: "guild", deps: []string{"user"}, events: &events})
+	mm.Register(&depModel{name: "user", deps: []string{"db"}, events: &events})
+	mm.Register(&depModel{name: "db", events: &events})
+	if st, _ := mm.State("guild"); st != StateRegistered {
+		t.Fatalf("state = %v", st)
+	}
+	if err := mm.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := mm.State("guild"); st != StateStarted {
+		t.Fatalf("state = %v", st)
+	}
+	mm.Register(&depModel{name: "chat", deps: []string{"user"}, events: &events})
+	if err := mm.Unregister("user"); err == nil {
+		t.Fatal("unregistered a model others depend on")
+	}
+	mm.Stop()
+	want := []string{
+		"init db", "init user", "init guild", "start db", "start user", "start guild",
+		"init chat", "start chat",
+		"stop chat", "stop guild", "stop user", "stop db",
+	}
+	if !slices.Equal(events, want) {
+		t.Fatalf("events = %v", events)
+	}
+	if st, _ := mm.State("db"); st != StateStopped {
+		t.Fatalf("state = %v", st)
+	}
+}
+
+func TestModelDependencyCycle(t *testing.T) {
+	var events []string
+	mm := NewModelManager()
+	defer mm.Stop()
+	mm.Register(&depModel{name: "a", deps: []string{"b"}, events: &events})
+	mm.Register(&depModel{name: "b", deps: []string{"a"}, events: &events})
+	if err := mm.Start(); err == nil || !strings.Contains(err.Error(), "cycle a -> b -> a") {
+		t.Fatalf("err = %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("events = %v", events)
+	}
+}
+
+func TestModelStartRollback(t *testing.T) {
+	var events []string
+	boom := errors.New("boom")
+	mm := NewModelManager()
+	defer mm.Stop()
+	mm.Register(&depModel{name: "a", events: &events})
+	mm.Register(&depModel{name: "b", deps: []string{"a"}, events: &events})
+	mm.Register(&depModel{name: "c", deps: []string{"b"}, startErr: boom, events: &events})
+	if err := mm.Start(); !errors.Is(err, boom) {
+		t.Fatalf("err = %v", err)
+	}
+	want := []string{"init a", "init b", "init c", "start a", "start b", "stop b", "stop a"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("events = %v", events)
+	}
+	for name, st := range map[string]State{"a": StateStopped, "b": StateStopped, "c": StateInitialized} {
+		if got, _ := mm.State(name); got != st {
+			t.Fatalf("%s state = %v, want %v", name, got, st)
+		}
+	}
+}
+
+func TestDispatchRequiresStartedModel(t *testing.T) {
+	var events []string
+	mm := NewModelManager()
+	mm.Register(&depModel{name: "user", events: &events})
+	handled := make(chan struct{}, 1)
+	RegisterHandler(&protos.C2SLogin{}, func(session.Session, protomessage.ProtoMessage) { handled <- struct{}{} })
+	defer Handlers.Delete((&protos.C2SLogin{}).MessageID())
+
+	s := testSession{session.NewNetworkEntities(1, -1)}
+	id := (&protos.C2SLogin{}).MessageID()
+	if err := mm.DispatchAsync(s, id, 0, nil); !errors.Is(err, ErrModelNotStarted) {
+		t.Fatalf("dispatch before Start err = %v", err)
+	}
+	if err := mm.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mm.DispatchAsync(s, id, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	<-handled
+	mm.Stop()
+	if err := mm.DispatchAsync(s, id, 0, nil); !errors.Is(err, ErrModelNotStarted) {
+		t.Fatalf("dispatch after Stop err = %v", err)
+	}
+}
+
+func TestStartAfterStop(t *testing.T) {
+	var events []string
+	mm := NewModelManager()
+	mm.Register(&depModel{name: "a", events: &events})
+	if err := mm.Start(); err != nil {
+		t.Fatal(err)
+	}
+	mm.Stop()
+	events = nil
+	if err := mm.Start(); !errors.Is(err, ErrModelStopped) {
+		t.Fatalf("err = %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("events = %v", events)
+	}
+	if st, _ := mm.State("a"); st != StateStopped {
+		t.Fatalf("state = %v", st)
+	}
+}
@@ -0,0 +1,136 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infra-foundation/session"
+	"sync"
+)
+
+// SessionMigrator is implemented by models keeping per-session state that
+// must follow a session moving to another node. ExportSession runs on the
+// source node once every message already queued for the session has been
+// handled, ImportSession on the target node before any new message.
+type SessionMigrator interface {
+	ExportSession(s session.Session) ([]byte, error)
+	ImportSession(s session.Session, state []byte) error
+}
+
+// ExportSession collects the state of s from every SessionMigrator model,
+// keyed by model name. Exporting hands the state over, so when a model
+// fails the states already exported are imported back before returning.
+func (m *ModelManager) ExportSession(ctx context.Context, s session.Session) (map[string][]byte, error) {
+	var (
+		mu     sync.Mutex
+		states = map[string][]byte{}
+	)
+	err := m.migrate(ctx, s, func(md *model, mg SessionMigrator) error {
+		state, err := mg.ExportSession(s)
+		if err != nil {
+			return fmt.Errorf("[ModelManager/ExportSession] %s %w", md.Name(), err)
+		}
+		mu.Lock()
+		states[md.Name()] = state
+		mu.Unlock()
+		return nil
+	})
+	if err != nil && len(states) > 0 {
+		if rerr := m.ImportSession(context.WithoutCancel(ctx), s, states); rerr != nil {
+			err = errors.Join(err, fmt.Errorf("[ModelManager/ExportSession] restore %w", rerr))
+		}
+		return nil, err
+	}
+	return states, err
+}
+
+// ImportSession hands the exported states to the SessionMigrator models.
+func (m *ModelManager) ImportSession(ctx context.Context, s session.Session, states map[string][]byte) error {
+	return m.migrate(ctx, s, func(md *model, mg SessionMigrator) error {
+		state, ok := states[md.Name()]
+		if !ok {
+			return nil
+		}
+		if err := mg.ImportSession(s, state); err != nil {
+			return fmt.Errorf("[ModelManager/ImportSession] %s %w", md.Name(), err)
+		}
+		return nil
+	})
+}
+
+// migrate runs f on the session shard of every SessionMigrator model after
+// draining the tasks already queued on all its shards.
+func (m *ModelManager) migrate(ctx context.Context, s session.Session, f func(*model, SessionMigrator) error) error {
+	m.mu.RLock()
+	var mds []*model
+	for _, name := range m.order {
+		if md, ok := m.modes[name]; ok {
+			if _, ok := md.Model.(SessionMigrator); ok {
+				mds = append(mds, md)
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	errs := make(chan error, len(mds))
+	for _, md := range mds {
+		go func() { errs <- md.migrate(ctx, s, f) }()
+	}
+	var first error
+	for range mds {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (m *model) migrate(ctx context.Context, s session.Session, f func(*model, SessionMigrator) error) error {
+	var wg sync.WaitGroup
+	for _, sh := range m.shards {
+		wg.Add(1)
+		if err := sh.Post(wg.Done); err != nil {
+			return fmt.Errorf("[ModelManager/migrate] %s %w", m.Name(), err)
+		}
+	}
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	// f runs at most once and only while migrate waits for it, so a caller
+	// giving up never misses a state f handed over.
+	var (
+		mu        sync.Mutex
+		abandoned bool
+	)
+	done := make(chan error, 1)
+	if err := m.shard(m.sessionKey(s, nil)).Post(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !abandoned {
+			done <- f(m, m.Model.(SessionMigrator))
+		}
+	}); err != nil {
+		return fmt.Errorf("[ModelManager/migrate] %s %w", m.Name(), err)
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	mu.Lock()
+	abandoned = true
+	mu.Unlock()
+	select {
+	case err := <-done:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"infra-foundation/session"
+	"testing"
+)
+
+// stateModel keeps one state per session and hands it over on export.
+type stateModel struct {
+	name     string
+	states   map[int64]string
+	exported chan struct{}
+	wait     chan struct{}
+	fail     error
+}
+
+func (m *stateModel) Name() string                    { return m.name }
+func (m *stateModel) OnInit() error                   { return nil }
+func (m *stateModel) OnStart() error                  { return nil }
+func (m *stateModel) OnStop() error                   { return nil }
+func (m *stateModel) OnDisconnection(session.Session) {}
+
+func (m *stateModel) ExportSession(s session.Session) ([]byte, error) {
+	if m.wait != nil {
+		<-m.wait
+	}
+	if m.fail != nil {
+		return nil, m.fail
+	}
+	state := m.states[s.ID()]
+	delete(m.states, s.ID())
+	if m.exported != nil {
+		close(m.exported)
+	}
+	return []byte(state), nil
+}
+
+func (m *stateModel) ImportSession(s session.Session, state []byte) error {
+	m.states[s.ID()] = string(state)
+	return nil
+}
+
+func TestExportSessionRestoresOnFailure(t *testing.T) {
+	mm := NewModelManager()
+	defer mm.Stop()
+	exported := make(chan struct{})
+	good := &stateModel{name: "good", states: map[int64]string{1: "gold"}, exported: exported}
+	bad := &stateModel{name: "bad", states: map[int64]string{}, wait: exported, fail: errors.New("disk full")}
+	mm.Register(good)
+	mm.Register(bad)
+	if err := mm.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := testSession{session.NewNetworkEntities(1, -1)}
+	states, err := mm.ExportSession(context.Background(), s)
+	if err == nil || states != nil {
+		t.Fatalf("states = %v err = %v", states, err)
+	}
+	md, _ := mm.GetModel("good")
+	state, _ := DoKey(context.Background(), md, uint64(s.ID()), func() (string, error) { return good.states[1], nil })
+	if state != "gold" {
+		t.Fatalf("state after failed export = %q", state)
+	}
+}
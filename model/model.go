@@ -1,11 +1,15 @@
 package model
 
 import (
+	"cmp"
 	"context"
+	"fmt"
 	protomessage "infra-foundation/protomessage"
 	"infra-foundation/scheduler"
 	"infra-foundation/session"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/protobuf/proto"
@@ -19,11 +23,18 @@ type Model interface {
 	OnDisconnection(session.Session)
 }
 
+type MailboxConfigurer interface {
+	MailboxConfig() scheduler.MailboxConfig
+}
+
 type handler struct {
 	model  *model
+	id     int32
 	name   string
+	msg    string
 	pbPool sync.Pool
 	handle session.HandlerFunc
+	reply  session.ReplyHandlerFunc
 }
 
 func (h *handler) Put(pb protomessage.ProtoMessage) {
@@ -50,46 +61,136 @@ func HandlersRoutes() []int32 {
 	return routes
 }
 
+type HandlerInfo struct {
+	ID      int32
+	Message string
+	Model   string
+}
+
+// LocalHandlers describes the handlers registered on this node.
+func LocalHandlers() []HandlerInfo {
+	var infos []HandlerInfo
+	Handlers.Range(func(key, value any) bool {
+		hd := value.(*handler)
+		infos = append(infos, HandlerInfo{ID: key.(int32), Message: hd.msg, Model: hd.name})
+		return true
+	})
+	slices.SortFunc(infos, func(a, b HandlerInfo) int { return cmp.Compare(a.ID, b.ID) })
+	return infos
+}
+
 func RegisterHandler(pb protomessage.ProtoMessage, hanHandlerFunc session.HandlerFunc) {
-	hd := &handler{name: pb.ModeName(), handle: hanHandlerFunc}
-	hd.pbPool = sync.Pool{New: func() any { return proto.Clone(pb) }}
+	hd := newHandler(pb)
+	hd.handle = hanHandlerFunc
 	Handlers.Store(pb.MessageID(), hd)
 }
 
+func newHandler(pb protomessage.ProtoMessage) *handler {
+	hd := &handler{id: pb.MessageID(), name: pb.ModeName(), msg: string(pb.ProtoReflect().Descriptor().FullName())}
+	hd.pbPool = sync.Pool{New: func() any { return proto.Clone(pb) }}
+	return hd
+}
+
 type model struct {
 	Model
 	mailbox *scheduler.Scheduler
+	shards  []*scheduler.Scheduler
+	keyer   ShardKeyer
+	asker   AskKeyer
+	state   atomic.Int32
+	closed  atomic.Bool
 }
 
 func newModel(m Model) *model {
-	return &model{mailbox: scheduler.NewScheduler(), Model: m}
+	var cfg scheduler.MailboxConfig
+	if c, ok := m.(MailboxConfigurer); ok {
+		cfg = c.MailboxConfig()
+	}
+	if cfg.Name == "" {
+		cfg.Name = m.Name()
+	}
+	md := &model{mailbox: scheduler.NewMailbox(cfg), Model: m}
+	md.shards = []*scheduler.Scheduler{md.mailbox}
+	if sm, ok := m.(ShardedModel); ok && sm.Shards() > 1 {
+		for i := 1; i < sm.Shards(); i++ {
+			shardCfg := cfg
+			shardCfg.Name = fmt.Sprintf("%s/%d", cfg.Name, i)
+			md.shards = append(md.shards, scheduler.NewMailboxOn(shardCfg, md.mailbox))
+		}
+	}
+	md.keyer, _ = m.(ShardKeyer)
+	md.asker, _ = m.(AskKeyer)
+	return md
 }
 
 func (m *model) PostFunc(f func()) {
 	m.mailbox.PushTask(f)
 }
 
+func (m *model) Post(f func()) error {
+	return m.mailbox.Post(f)
+}
+
+func (m *model) PostFuncKey(key uint64, f func()) {
+	m.shard(key).PushTask(f)
+}
+
+func (m *model) PostKey(key uint64, f func()) error {
+	return m.shard(key).Post(f)
+}
+
+// Clock is the clock driving the model's mailboxes and timers.
+func (m *model) Clock() scheduler.Clock {
+	return m.mailbox.Clock()
+}
+
+func (m *model) MailboxStats() scheduler.MailboxStats {
+	return m.mailbox.Stats()
+}
+
+func (m *model) ShardStats() []scheduler.MailboxStats {
+	stats := make([]scheduler.MailboxStats, len(m.shards))
+	for i, sh := range m.shards {
+		stats[i] = sh.Stats()
+	}
+	return stats
+}
+
 func (m *model) PushInfiniteTimer(interval time.Duration, infiniter bool, f func()) scheduler.TimerID {
 	return m.mailbox.PushInfiniteTimer(interval, infiniter, f)
 }
 
+// PushInfiniteTimerKey is PushInfiniteTimer on the shard of key. Cancel the
+// timer with CancelTimerKey and the same key.
+func (m *model) PushInfiniteTimerKey(key uint64, interval time.Duration, infiniter bool, f func()) scheduler.TimerID {
+	return m.shard(key).PushInfiniteTimer(interval, infiniter, f)
+}
+
+func (m *model) PushCron(expr string, f func()) (*scheduler.CronJob, error) {
+	return m.mailbox.PushCron(expr, f)
+}
+
 func (m *model) CancelTimer(id scheduler.TimerID) bool {
 	return m.mailbox.CancelTimer(id)
 }
 
+func (m *model) CancelTimerKey(key uint64, id scheduler.TimerID) bool {
+	return m.shard(key).CancelTimer(id)
+}
+
 func (m *model) DoAsync(md *model, cb func()) {
 	md.mailbox.PushTask(cb)
 }
 
 func (m *model) OnDisconnection(s session.Session) {
-	m.mailbox.PushTask(func() { m.Model.OnDisconnection(s) })
+	m.shard(m.sessionKey(s, nil)).PushTask(func() { m.Model.OnDisconnection(s) })
 }
 
-func (m *model) Stop() {
-	if m.Model != nil {
-		m.Model.OnStop()
+func (m *model) closeMailbox() {
+	m.closed.Store(true)
+	for i := len(m.shards) - 1; i >= 0; i-- {
+		m.shards[i].Stop()
 	}
-	m.mailbox.Stop()
 }
 
 type result[T any] struct {
@@ -98,9 +199,17 @@ type result[T any] struct {
 }
 
 func Do[T any](ctx context.Context, m *model, fn func() (T, error)) (T, error) {
+	return do(ctx, m.PostFunc, fn)
+}
+
+func DoKey[T any](ctx context.Context, m *model, key uint64, fn func() (T, error)) (T, error) {
+	return do(ctx, func(f func()) { m.PostFuncKey(key, f) }, fn)
+}
+
+func do[T any](ctx context.Context, post func(func()), fn func() (T, error)) (T, error) {
 	resultChan := make(chan result[T], 1)
 
-	m.PostFunc(func() {
+	post(func() {
 		val, err := fn()
 		resultChan <- result[T]{val, err}
 	})
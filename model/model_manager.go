@@ -4,16 +4,22 @@ import (
 	"errors"
 	"fmt"
 	protomessage "infra-foundation/protomessage"
+	"infra-foundation/scheduler"
 	"infra-foundation/session"
+	"slices"
 	"sync"
 
 	"google.golang.org/protobuf/proto"
 )
 
 type ModelManager struct {
-	mu    sync.RWMutex
-	modes map[string]*model
-	order []string
+	mu        sync.RWMutex
+	lifecycle sync.Mutex
+	modes     map[string]*model
+	order     []string
+	started   bool
+
+	handlerErrors sync.Map
 }
 
 func NewModelManager() *ModelManager {
@@ -22,6 +28,9 @@ func NewModelManager() *ModelManager {
 
 var DefaultModelManager = NewModelManager()
 
+// Register adds model in the Registered state. Its OnInit and OnStart run
+// from Start in dependency order, or right away when the manager has already
+// been started, in which case its dependencies must be started too.
 func (m *ModelManager) Register(model Model) error {
 	if model == nil {
 		return errors.New("model.Manager.Register: model is nil")
@@ -30,33 +39,46 @@ func (m *ModelManager) Register(model Model) error {
 	if name == "" {
 		return errors.New("model.Manager.Register: Name is empty")
 	}
-	if err := model.OnInit(); err != nil {
-		return fmt.Errorf("model.Manager.Register: OnInit %w", err)
-	}
-	if err := model.OnStart(); err != nil {
-		return fmt.Errorf("model.Manager.Register: OnStart %w", err)
-	}
+	m.lifecycle.Lock()
+	defer m.lifecycle.Unlock()
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	if _, exists := m.modes[name]; exists {
+		m.mu.Unlock()
 		return fmt.Errorf("model.Manager.Register: duplicated model name %q", name)
 	}
-	m.modes[name] = newModel(model)
-
+	md := newModel(model)
+	m.modes[name] = md
 	m.order = append(m.order, name)
+	m.mu.Unlock()
+
+	if !m.started {
+		return nil
+	}
+	if err := m.startOne(name, md); err != nil {
+		m.remove(name)
+		md.closeMailbox()
+		return fmt.Errorf("model.Manager.Register: %w", err)
+	}
 	return nil
 }
 
+// Stop stops the started models in reverse dependency order and closes every
+// mailbox.
 func (m *ModelManager) Stop() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	for i := len(m.order) - 1; i >= 0; i-- {
-		w, ok := m.modes[m.order[i]]
+	m.lifecycle.Lock()
+	defer m.lifecycle.Unlock()
+	m.mu.RLock()
+	order := append([]string(nil), m.order...)
+	m.mu.RUnlock()
+	for i := len(order) - 1; i >= 0; i-- {
+		w, ok := m.GetModel(order[i])
 		if !ok {
 			continue
 		}
-		w.Stop()
+		w.stopModel()
+		w.closeMailbox()
 	}
+	m.started = false
 	return nil
 }
 
@@ -80,24 +102,36 @@ func (m *ModelManager) GetModel(name string) (*model, bool) {
 }
 
 func (m *ModelManager) Unregister(name string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	model, ok := m.modes[name]
+	m.lifecycle.Lock()
+	defer m.lifecycle.Unlock()
+	model, ok := m.GetModel(name)
 	if !ok {
 		return fmt.Errorf("model.Manager.Unregister: %q not found", name)
 	}
-	model.Stop()
-	delete(m.modes, name)
-	for i, n := range m.order {
-		if n == name {
-			m.order = append(m.order[:i], m.order[i+1:]...)
-			break
+	m.mu.RLock()
+	for _, other := range m.modes {
+		if other.State() == StateStarted && slices.Contains(dependencies(other.Model), name) {
+			m.mu.RUnlock()
+			return fmt.Errorf("model.Manager.Unregister: %q is required by %q", name, other.Name())
 		}
 	}
+	m.mu.RUnlock()
+	model.stopModel()
+	model.closeMailbox()
+	m.remove(name)
 	return nil
 }
 
-func (m *ModelManager) DispatchAsync(session session.Session, id int32, msg []byte) error {
+func (m *ModelManager) remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.modes, name)
+	m.order = slices.DeleteFunc(m.order, func(n string) bool { return n == name })
+}
+
+// DispatchAsync runs the handler of message id for msg on the mailbox of its
+// model. seq is the header sequence of the request, echoed by reply handlers.
+func (m *ModelManager) DispatchAsync(session session.Session, id int32, seq uint32, msg []byte) error {
 	value, ok := Handlers.Load(id)
 	if !ok {
 		return fmt.Errorf("[ModelManager/DispatchLocalAsync] %d handlers not found", id)
@@ -116,6 +150,9 @@ func (m *ModelManager) DispatchAsync(session session.Session, id int32, msg []by
 		}
 		hand.model = md
 	}
+	if st := md.State(); st != StateStarted {
+		return fmt.Errorf("[ModelManager/DispatchLocalAsync] %s is %s %w", hand.name, st, ErrModelNotStarted)
+	}
 
 	var pb protomessage.ProtoMessage
 	if len(msg) > 0 {
@@ -126,9 +163,28 @@ func (m *ModelManager) DispatchAsync(session session.Session, id int32, msg []by
 		}
 	}
 
-	md.PostFunc(func() {
-		hand.handle(session, pb)
+	if err := md.PostKey(md.sessionKey(session, pb), func() {
+		m.handle(hand, session, seq, pb)
 		hand.Put(pb)
-	})
+	}); err != nil {
+		hand.Put(pb)
+		return fmt.Errorf("[ModelManager/DispatchLocalAsync] %d model %s %w", id, hand.name, err)
+	}
 	return nil
 }
+
+func (m *ModelManager) MailboxStats() map[string]scheduler.MailboxStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stats := make(map[string]scheduler.MailboxStats, len(m.modes))
+	for name, md := range m.modes {
+		if len(md.shards) == 1 {
+			stats[name] = md.MailboxStats()
+			continue
+		}
+		for i, st := range md.ShardStats() {
+			stats[fmt.Sprintf("%s/%d", name, i)] = st
+		}
+	}
+	return stats
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"errors"
+	"infra-foundation/logx"
+	protomessage "infra-foundation/protomessage"
+	"infra-foundation/session"
+	"sync/atomic"
+)
+
+type ErrorKey struct {
+	MessageID int32
+	Code      int32
+}
+
+// RegisterReplyHandler registers a handler whose reply is sent to the session
+// and whose error is sent back as an error packet. Errors without a
+// *session.CodeError are logged and reported as session.CodeInternal.
+func RegisterReplyHandler(pb protomessage.ProtoMessage, fn session.ReplyHandlerFunc) {
+	hd := newHandler(pb)
+	hd.reply = fn
+	Handlers.Store(pb.MessageID(), hd)
+}
+
+// Handle is the typed form of RegisterReplyHandler.
+func Handle[Req, Resp protomessage.ProtoMessage](fn func(session.Session, Req) (Resp, error)) {
+	var req Req
+	RegisterReplyHandler(req.ProtoReflect().Type().New().Interface().(protomessage.ProtoMessage), func(s session.Session, pb protomessage.ProtoMessage) (protomessage.ProtoMessage, error) {
+		resp, err := fn(s, pb.(Req))
+		if err != nil {
+			return nil, err
+		}
+		if resp.ProtoReflect().IsValid() {
+			return resp, nil
+		}
+		return nil, nil
+	})
+}
+
+// handle runs h for pb and, for reply handlers, answers the request seq with
+// the reply or the error.
+func (m *ModelManager) handle(h *handler, s session.Session, seq uint32, pb protomessage.ProtoMessage) {
+	if h.reply == nil {
+		h.handle(s, pb)
+		return
+	}
+	id := h.id
+	reply, err := h.reply(s, pb)
+	if err != nil {
+		m.replyError(s, seq, id, err)
+		return
+	}
+	if reply == nil {
+		return
+	}
+	if r, ok := s.(session.Replier); ok {
+		err = r.Reply(seq, reply)
+	} else {
+		err = s.Send(reply)
+	}
+	if err != nil {
+		logx.Err.Printf("[model/Reply] MessageID[%d] SessionID[%d] Send %v", id, s.ID(), err)
+	}
+}
+
+func (m *ModelManager) replyError(s session.Session, seq uint32, id int32, err error) {
+	code, msg := session.CodeInternal, "internal error"
+	if ce, ok := errors.AsType[*session.CodeError](err); ok {
+		code, msg = ce.Code, ce.Message
+	} else {
+		logx.Err.Printf("[model/Reply] MessageID[%d] SessionID[%d] %v", id, s.ID(), err)
+	}
+	v, _ := m.handlerErrors.LoadOrStore(ErrorKey{id, code}, new(atomic.Uint64))
+	v.(*atomic.Uint64).Add(1)
+
+	r, ok := s.(session.Replier)
+	if !ok {
+		return
+	}
+	if err = r.ReplyError(seq, id, code, msg); err != nil {
+		logx.Err.Printf("[model/Reply] MessageID[%d] SessionID[%d] ReplyError %v", id, s.ID(), err)
+	}
+}
+
+// HandlerErrors returns how many handler errors were returned per request
+// message and code.
+func (m *ModelManager) HandlerErrors() map[ErrorKey]uint64 {
+	stats := map[ErrorKey]uint64{}
+	m.handlerErrors.Range(func(k, v any) bool {
+		stats[k.(ErrorKey)] = v.(*atomic.Uint64).Load()
+		return true
+	})
+	return stats
+}
+
+// HandlerErrors returns the handler error counts of DefaultModelManager.
+func HandlerErrors() map[ErrorKey]uint64 {
+	return DefaultModelManager.HandlerErrors()
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"errors"
+	"infra-foundation/example/protos"
+	protomessage "infra-foundation/protomessage"
+	"infra-foundation/session"
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type replySession struct {
+	testSession
+	sent   []protomessage.ProtoMessage
+	seqs   []uint32
+	errors []int32
+}
+
+func (r *replySession) Reply(seq uint32, pb protomessage.ProtoMessage) error {
+	r.sent = append(r.sent, pb)
+	r.seqs = append(r.seqs, seq)
+	return nil
+}
+
+func (r *replySession) ReplyError(seq uint32, id int32, code int32, msg string) error {
+	r.errors = append(r.errors, code)
+	r.seqs = append(r.seqs, seq)
+	return nil
+}
+
+func TestReplyHandler(t *testing.T) {
+	Handle(func(s session.Session, req *protos.C2SLogin) (*protos.S2CLogin, error) {
+		switch req.Name {
+		case "":
+			return nil, session.NewError(7, "name required")
+		case "panic":
+			return nil, errors.New("db down")
+		}
+		return &protos.S2CLogin{Name: req.Name}, nil
+	})
+	defer Handlers.Delete((&protos.C2SLogin{}).MessageID())
+	v, _ := Handlers.Load((&protos.C2SLogin{}).MessageID())
+	hd := v.(*handler)
+
+	mm := NewModelManager()
+	s := &replySession{testSession: testSession{session.NewNetworkEntities(1, 1)}}
+	for i, name := range []string{"a", "", "panic"} {
+		mm.handle(hd, s, uint32(i+1), &protos.C2SLogin{Name: name})
+	}
+	if !slices.Equal(s.seqs, []uint32{1, 2, 3}) {
+		t.Fatalf("seqs = %v", s.seqs)
+	}
+	if len(s.sent) != 1 || !proto.Equal(s.sent[0], &protos.S2CLogin{Name: "a"}) {
+		t.Fatalf("sent = %v", s.sent)
+	}
+	if len(s.errors) != 2 || s.errors[0] != 7 || s.errors[1] != session.CodeInternal {
+		t.Fatalf("errors = %v", s.errors)
+	}
+	id := (&protos.C2SLogin{}).MessageID()
+	stats := mm.HandlerErrors()
+	if stats[ErrorKey{id, 7}] != 1 || stats[ErrorKey{id, session.CodeInternal}] != 1 {
+		t.Fatalf("stats = %v", stats)
+	}
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	protomessage "infra-foundation/protomessage"
+	"infra-foundation/scheduler"
+	"infra-foundation/session"
+)
+
+// ShardedModel spreads a model over Shards() mailboxes. Work for the same key
+// always lands on the same mailbox and stays ordered, different keys run in
+// parallel. Lifecycle hooks and unkeyed timers run on shard 0; use
+// PushInfiniteTimerKey to run a timer on the shard of a key.
+type ShardedModel interface {
+	Model
+	Shards() int
+}
+
+// ShardKeyer overrides the default shard key (the session ID) for messages and
+// session events dispatched to a sharded model. The key of a session must not
+// change during its lifetime, or its work stops being ordered.
+type ShardKeyer interface {
+	ShardKey(s session.Session, pb protomessage.ProtoMessage) uint64
+}
+
+// AskKeyer picks the shard of asks, which carry no session and so never reach
+// ShardKey. Sharded models without one run every ask on shard 0.
+type AskKeyer interface {
+	AskShardKey(req protomessage.ProtoMessage) uint64
+}
+
+func (m *model) shard(key uint64) *scheduler.Scheduler {
+	if len(m.shards) == 1 {
+		return m.shards[0]
+	}
+	return m.shards[Mix64(key)%uint64(len(m.shards))]
+}
+
+func (m *model) sessionKey(s session.Session, pb protomessage.ProtoMessage) uint64 {
+	if len(m.shards) == 1 || s == nil {
+		return 0
+	}
+	if m.keyer != nil {
+		return m.keyer.ShardKey(s, pb)
+	}
+	return uint64(s.ID())
+}
+
+// Mix64 spreads the bits of x so that sequential keys land on different
+// shards. Cluster placement hashing reuses it.
+func Mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
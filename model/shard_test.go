@@ -0,0 +1,82 @@
+package model
+
+import (
+	"context"
+	"infra-foundation/session"
+	"sync"
+	"testing"
+	"time"
+)
+
+type shardedModel struct{ shards int }
+
+func (s *shardedModel) Name() string                    { return "sharded" }
+func (s *shardedModel) OnInit() error                   { return nil }
+func (s *shardedModel) OnStart() error                  { return nil }
+func (s *shardedModel) OnStop() error                   { return nil }
+func (s *shardedModel) Shards() int                     { return s.shards }
+func (s *shardedModel) OnDisconnection(session.Session) {}
+
+func TestShardedModelOrdering(t *testing.T) {
+	md := newModel(&shardedModel{shards: 4})
+	defer md.closeMailbox()
+	if len(md.shards) != 4 {
+		t.Fatalf("shards = %d", len(md.shards))
+	}
+
+	var mu sync.Mutex
+	seen := map[uint64][]int{}
+	for i := range 1000 {
+		key := uint64(i % 10)
+		md.PostFuncKey(key, func() {
+			mu.Lock()
+			seen[key] = append(seen[key], i)
+			mu.Unlock()
+		})
+	}
+	for key := range uint64(10) {
+		if _, err := DoKey(context.Background(), md, key, func() (struct{}, error) { return struct{}{}, nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for key, order := range seen {
+		if len(order) != 100 {
+			t.Fatalf("key %d got %d tasks", key, len(order))
+		}
+		for j := 1; j < len(order); j++ {
+			if order[j] < order[j-1] {
+				t.Fatalf("key %d out of order: %v", key, order)
+			}
+		}
+	}
+}
+
+func TestShardKeyStableAcrossLogin(t *testing.T) {
+	md := newModel(&shardedModel{shards: 8})
+	defer md.closeMailbox()
+	s := testSession{session.NewNetworkEntities(3, -1)}
+	before := md.shard(md.sessionKey(s, nil))
+	for uid := range int64(32) {
+		s.BindUID(uid + 1)
+		if md.shard(md.sessionKey(s, nil)) != before {
+			t.Fatalf("uid %d moved the session to another shard", uid+1)
+		}
+	}
+}
+
+func TestShardTimerKey(t *testing.T) {
+	md := newModel(&shardedModel{shards: 4})
+	defer md.closeMailbox()
+	const key = 5
+	fired := make(chan struct{}, 1)
+	md.PushInfiniteTimerKey(key, time.Millisecond, false, func() { fired <- struct{}{} })
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("timer did not fire")
+	}
+	id := md.PushInfiniteTimerKey(key, time.Hour, true, func() {})
+	if !md.CancelTimerKey(key, id) {
+		t.Fatal("CancelTimerKey = false")
+	}
+}
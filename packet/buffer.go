@@ -0,0 +1,53 @@
+package packet
+
+import (
+	"math/bits"
+	"sync"
+)
+
+const (
+	minBufferShift = 8  // 256 bytes
+	maxBufferShift = 16 // 64 KiB
+)
+
+var bufferPools [maxBufferShift - minBufferShift + 1]sync.Pool
+
+// Buffer is a pooled byte slice holding packed packets. Free returns it to
+// the pool, after which B must not be used.
+type Buffer struct {
+	B     []byte
+	class int
+}
+
+// GetBuffer returns a buffer of length n. Buffers above 64 KiB are not
+// pooled.
+func GetBuffer(n int) *Buffer {
+	class := bufferClass(n)
+	if class < 0 {
+		return &Buffer{B: make([]byte, n), class: -1}
+	}
+	if b, ok := bufferPools[class].Get().(*Buffer); ok {
+		b.B = b.B[:n]
+		return b
+	}
+	return &Buffer{B: make([]byte, n, 1<<(class+minBufferShift)), class: class}
+}
+
+func (b *Buffer) Free() {
+	if b == nil || b.class < 0 {
+		return
+	}
+	b.B = b.B[:0]
+	bufferPools[b.class].Put(b)
+}
+
+func bufferClass(n int) int {
+	if n <= 1<<minBufferShift {
+		return 0
+	}
+	shift := bits.Len(uint(n - 1))
+	if shift > maxBufferShift {
+		return -1
+	}
+	return shift - minBufferShift
+}
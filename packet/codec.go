@@ -2,8 +2,11 @@ package packet
 
 import (
 	"bytes"
+	"crypto/ecdh"
 	"encoding/binary"
 	"errors"
+	"sync"
+	"sync/atomic"
 
 	"github.com/cloudwego/netpoll"
 )
@@ -16,198 +19,266 @@ const (
 var (
 	ErrWrongPacketType  = errors.New("codec: wrong packet type")
 	ErrPacketSizeExcced = errors.New("codec: packet size exceed")
+	ErrPacketLength     = errors.New("codec: packet length does not match its frame")
 )
 
+// PackCodec frames packets. It decodes both header versions and packs with
+// its current version, which starts at V1 and moves to V2 once a V2 packet
+// is received, so a peer only gets V2 packets after sending one itself.
 type PackCodec struct {
-	buf  *bytes.Buffer
-	size int32
-	Id   int32
-	sid  int64
-	typ  Type
+	buf     *bytes.Buffer
+	err     error
+	Id      int32
+	version atomic.Uint32
+	pinned  bool
+
+	compression atomic.Uint32
+	handshakeMu sync.Mutex
+	threshold   int
+	algs        []Compression
+	offered     bool
+
+	encrypt bool
+	priv    *ecdh.PrivateKey
+	sealer  atomic.Pointer[cipherState]
+	pending atomic.Pointer[cipherState]
+	opener  atomic.Pointer[cipherState]
 }
 
 func NewPackCodec() *PackCodec {
-	return &PackCodec{buf: bytes.NewBuffer(nil), size: -1}
+	p := &PackCodec{buf: bytes.NewBuffer(nil)}
+	p.version.Store(uint32(V1))
+	return p
 }
 
-func (p *PackCodec) Pack(typ Type, id int32, sid int64, payload []byte) ([]byte, error) {
-	if typ < Heartbeat || typ >= Invalid {
-		return nil, ErrWrongPacketType
-	}
-	payloadLen := uint32(len(payload))
-	total := HeadLength
-	if p.isSidOffset(typ) {
-		total += 8
-	}
-	total += int(payloadLen)
+// NewPackCodecWithVersion returns a codec packing with v from the first
+// packet and never changing version, for peers known to speak v.
+func NewPackCodecWithVersion(v Version) *PackCodec {
+	p := NewPackCodec()
+	p.version.Store(uint32(v))
+	p.pinned = true
+	return p
+}
 
-	buf := make([]byte, total)
+func (p *PackCodec) Version() Version {
+	return Version(p.version.Load())
+}
 
-	binary.BigEndian.PutUint32(buf[:4], uint32(total))
-	buf[4] = byte(typ)
-	binary.BigEndian.PutUint32(buf[5:9], uint32(id))
+func (p *PackCodec) negotiate(v Version) {
+	if !p.pinned && v > p.Version() {
+		p.version.Store(uint32(v))
+	}
+}
 
-	offset := HeadLength
+func (p *PackCodec) Pack(typ Type, id int32, sid int64, payload []byte) ([]byte, error) {
+	return p.PackHeader(Header{Type: typ, ID: id, SID: sid}, payload)
+}
 
-	if p.isSidOffset(typ) {
-		binary.BigEndian.PutUint64(buf[offset:offset+8], uint64(sid))
-		offset += 8
+// PackHeader packs payload under h, compressing it when the codec negotiated
+// compression. Flags, sequence and extensions need the codec to have
+// negotiated V2.
+func (p *PackCodec) PackHeader(h Header, payload []byte) ([]byte, error) {
+	payload, v, err := p.prepare(&h, payload)
+	if err != nil {
+		return nil, err
 	}
-
-	copy(buf[offset:], payload)
-	return buf, nil
+	return h.pack(v, payload), nil
 }
 
-func (p *PackCodec) isSidOffset(typ Type) bool {
-	return typ == ClientData || typ == InternalData
+// PackBuffer is PackHeader into a pooled buffer.
+func (p *PackCodec) PackBuffer(h Header, payload []byte) (*Buffer, error) {
+	payload, v, err := p.prepare(&h, payload)
+	if err != nil {
+		return nil, err
+	}
+	hlen := h.length(v)
+	b := GetBuffer(hlen + len(payload))
+	h.put(b.B, v, len(b.B))
+	copy(b.B[hlen:], payload)
+	return b, nil
 }
 
-func (p *PackCodec) NextPacket(reader netpoll.Reader) (netpoll.Reader, error) {
-	bLen, err := reader.Peek(4)
+// PackNested packs payload under inner and that packet under outer, as
+// PackHeader(outer, PackHeader(inner, payload)) would, writing both headers
+// in place in one pooled buffer instead of copying the inner packet.
+func (p *PackCodec) PackNested(outer, inner Header, payload []byte) (*Buffer, error) {
+	payload, v, err := p.prepare(&inner, payload)
 	if err != nil {
-		if err == netpoll.ErrEOF {
-			return nil, nil
-		}
 		return nil, err
 	}
-	pkLen := int(binary.BigEndian.Uint32(bLen))
-	if pkLen > reader.Len() {
-		return nil, nil
+	ilen := inner.length(v) + len(payload)
+	if p.compressible(&outer, ilen) {
+		b := GetBuffer(ilen)
+		defer b.Free()
+		inner.put(b.B, v, ilen)
+		copy(b.B[inner.length(v):], payload)
+		return p.PackBuffer(outer, b.B)
 	}
-	if pkLen > MaxPacketSize {
-		return nil, ErrPacketSizeExcced
+	if _, _, err = p.prepare(&outer, nil); err != nil {
+		return nil, err
+	}
+	olen := outer.length(v)
+	b := GetBuffer(olen + ilen)
+	outer.put(b.B, v, len(b.B))
+	inner.put(b.B[olen:], v, ilen)
+	copy(b.B[olen+inner.length(v):], payload)
+	return b, nil
+}
+
+// Reframe packs again with p the single packet b packed by another codec,
+// so that a gate forwarding the packets a backend packed for a client
+// compresses them as the client negotiated. It returns nil when b is not
+// worth compressing and can be sent as it is.
+func (p *PackCodec) Reframe(b []byte) (*Buffer, error) {
+	if p.Compression() == CompressionNone || len(b) < 5 {
+		return nil, nil
 	}
-	btyp, err := reader.Peek(5)
+	n, err := checkFrame(b)
 	if err != nil {
 		return nil, err
 	}
-	typ := Type(btyp[4])
-	if typ < Heartbeat || typ >= Invalid {
-		return nil, ErrWrongPacketType
+	if n != len(b) {
+		return nil, &ProtocolError{Type: Type(b[4] &^ v2Bit), Length: len(b), Err: ErrPacketLength}
 	}
-	r2, err := reader.Slice(pkLen)
+	h, hlen, _, err := parseHeader(b)
 	if err != nil {
 		return nil, err
 	}
-	return r2, nil
+	if h.Flags&FlagCompressed != 0 || !p.compressible(&h, len(b)-hlen) {
+		return nil, nil
+	}
+	return p.PackBuffer(h, b[hlen:])
 }
 
-func (p *PackCodec) Unpack1(reader netpoll.Reader) (*Packet, error) {
-	bPkLen, err := reader.Next(4)
+// prepare validates h for packing payload with the codec version and
+// compresses payload when worth it.
+func (p *PackCodec) prepare(h *Header, payload []byte) ([]byte, Version, error) {
+	if h.Type < Heartbeat || h.Type >= Invalid {
+		return nil, 0, ErrWrongPacketType
+	}
+	payload, err := p.compress(h, payload)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	v := p.Version()
+	if v < V2 && h.isV2() {
+		return nil, 0, ErrHeaderVersion
+	}
+	for _, e := range h.Ext {
+		if len(e.Value) > maxExtValueLen {
+			return nil, 0, ErrBadExtension
+		}
 	}
-	pkLen := int(binary.BigEndian.Uint32(bPkLen))
+	return payload, v, nil
+}
 
-	btyp, err := reader.Next(1)
+// NextPacket slices the next packet off reader once it is complete. A
+// malformed frame is rejected from its first 5 bytes with a *ProtocolError,
+// after which the stream cannot be read any further.
+func (p *PackCodec) NextPacket(reader netpoll.Reader) (netpoll.Reader, error) {
+	b, err := reader.Peek(5)
 	if err != nil {
+		if err == netpoll.ErrEOF {
+			return nil, nil
+		}
 		return nil, err
 	}
-
-	typ := Type(btyp[0])
-
-	bId, err := reader.Next(4)
+	pkLen, err := checkFrame(b)
 	if err != nil {
 		return nil, err
 	}
-	id := int32(binary.BigEndian.Uint32(bId))
-
-	var offset int = HeadLength
-	var sid int64 = 0
-	if p.isSidOffset(typ) {
-		bsid, err := reader.Next(8)
-		if err != nil {
-			return nil, err
-		}
-		sid = int64(binary.BigEndian.Uint64(bsid))
-		offset += 8
+	if pkLen > reader.Len() {
+		return nil, nil
 	}
+	return reader.Slice(pkLen)
+}
 
-	payload, _ := reader.Next(pkLen - offset)
-
+// Unpack1 decodes the single packet sliced by NextPacket.
+func (p *PackCodec) Unpack1(reader netpoll.Reader) (*Packet, error) {
+	b, err := reader.Next(reader.Len())
+	if err != nil {
+		return nil, err
+	}
 	_ = reader.Release()
-	switch typ {
-	case InternalData, ClientData:
-		return NewInternal(typ, id, sid, payload), nil
-	default:
-		return New(typ, id, payload), nil
+	if len(b) < 5 {
+		return nil, &ProtocolError{Length: len(b), Err: ErrPacketTooShort}
+	}
+	pkLen, err := checkFrame(b)
+	if err != nil {
+		return nil, err
 	}
+	if pkLen != len(b) {
+		return nil, &ProtocolError{Type: Type(b[4] &^ v2Bit), Length: len(b), Err: ErrPacketLength}
+	}
+	h, payload, err := p.decode(b)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithHeader(h, payload), nil
 }
 
+// Unpack decodes the packets completed by data in the stream. A packet
+// failing to decode is dropped and its error returned with the packets
+// before it; the next call goes on after it. A malformed frame breaks the
+// stream, and every later call returns its error.
+//
+// Unpack decodes every packet before returning any, so a handshake answer
+// cannot take effect for the packets after it in data. A peer negotiating
+// compression or encryption reads with Feed and Next instead.
 func (p *PackCodec) Unpack(data []byte) ([]*Packet, error) {
-	if len(data) > 0 {
-		if _, err := p.buf.Write(data); err != nil {
-			return nil, err
-		}
+	if err := p.Feed(data); err != nil {
+		return nil, err
 	}
-
 	var packets []*Packet
-
 	for {
-		if p.size < 0 {
-
-			if p.buf.Len() < HeadLength {
-				break
-			}
-
-			b := p.buf.Bytes()[:HeadLength]
-			pkLen := int32(binary.BigEndian.Uint32(b[:4]))
-
-			if p.buf.Len() < int(pkLen) {
-				break
-			}
-
-			typ := Type(b[4])
-			if typ < Heartbeat || typ >= Invalid {
-				return packets, ErrWrongPacketType
-			}
-
-			offset := HeadLength
-			if p.isSidOffset(typ) {
-				offset += 8
-			}
-
-			id := int32(binary.BigEndian.Uint32(b[5:9]))
-
-			payloadLen := int32(pkLen - int32(offset))
-			if payloadLen < 0 || payloadLen > MaxPacketSize {
-				return packets, ErrPacketSizeExcced
-			}
-
-			p.buf.Next(HeadLength)
-
-			if p.isSidOffset(typ) {
-				p.sid = int64(binary.BigEndian.Uint64(p.buf.Next(8)))
-			} else {
-				p.sid = 0
-			}
-
-			p.typ = typ
-			p.Id = id
-			p.size = payloadLen
+		pk, err := p.Next()
+		if err != nil || pk == nil {
+			return packets, err
 		}
+		packets = append(packets, pk)
+	}
+}
 
-		if p.size == -1 || p.buf.Len() < int(p.size) {
-			break
+// Feed appends data read from the stream for Next to decode.
+func (p *PackCodec) Feed(data []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+	if len(data) > 0 {
+		if _, err := p.buf.Write(data); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		payload := p.buf.Next(int(p.size))
-
-		var pkt *Packet
-		switch p.typ {
-		case InternalData, ClientData:
-			pkt = NewInternal(p.typ, p.Id, p.sid, payload)
-		default:
-			pkt = New(p.typ, p.Id, payload)
+// Next decodes the next packet completed in the stream, or returns nil when
+// it needs more data. Decoding one packet at a time lets the caller Accept a
+// handshake answer before the packets following it are decoded. Errors are
+// reported as by Unpack.
+func (p *PackCodec) Next() (*Packet, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.buf.Len() < 5 {
+		return nil, nil
+	}
+	pkLen, err := checkFrame(p.buf.Bytes())
+	if err != nil {
+		p.err = err
+		p.buf.Reset()
+		return nil, err
+	}
+	if p.buf.Len() < pkLen {
+		if p.buf.Len() >= 9 {
+			p.Id = int32(binary.BigEndian.Uint32(p.buf.Bytes()[5:9]))
 		}
-
-		packets = append(packets, pkt)
-
-		p.size = -1
-		p.sid = 0
-		p.typ = 0
-		p.Id = 0
+		return nil, nil
 	}
-
-	return packets, nil
+	h, payload, err := p.decode(p.buf.Next(pkLen))
+	if err != nil {
+		return nil, err
+	}
+	p.Id = 0
+	return NewWithHeader(h, payload), nil
 }
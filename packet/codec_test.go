@@ -1,6 +1,8 @@
 package packet
 
 import (
+	"bytes"
+	"errors"
 	"math"
 	"testing"
 
@@ -30,3 +32,372 @@ func TestPackCodec(t *testing.T) {
 	bufdata.Flush()
 	t.Logf("% X", bufdata.Bytes())
 }
+
+func TestPackCodecV2(t *testing.T) {
+	const flagApp Flags = 1 << 6 // no codec meaning, carried as is
+	server, client := NewPackCodec(), NewPackCodecWithVersion(V2)
+	h := Header{Type: InternalData, ID: 7, SID: 42, Flags: flagApp, Seq: 9, Ext: []Extension{{Key: ExtTraceID, Value: []byte("trace-1")}}}
+
+	if _, err := server.PackHeader(h, nil); !errors.Is(err, ErrHeaderVersion) {
+		t.Fatalf("v1 codec packed a v2 header: %v", err)
+	}
+	legacy, _ := server.Pack(Data, 1, 0, []byte("old"))
+	if ps, err := client.Unpack(legacy); err != nil || len(ps) != 1 || string(ps[0].Data()) != "old" {
+		t.Fatalf("v1 packet = %v %v", ps, err)
+	}
+	if client.Version() != V2 {
+		t.Fatal("pinned codec downgraded")
+	}
+
+	b, err := client.PackHeader(h, []byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps, err := server.Unpack(append(b[:len(b):len(b)], legacy...))
+	if err != nil || len(ps) != 2 {
+		t.Fatalf("packets = %v %v", ps, err)
+	}
+	got := ps[0].Header()
+	trace, _ := got.Extension(ExtTraceID)
+	if got.Type != InternalData || got.ID != 7 || got.SID != 42 || got.Seq != 9 || got.Flags&flagApp == 0 || string(trace) != "trace-1" || string(ps[0].Data()) != "payload" {
+		t.Fatalf("header = %+v data %q", got, ps[0].Data())
+	}
+	if ps[1].Type() != Data || string(ps[1].Data()) != "old" {
+		t.Fatalf("v1 after v2 = %v", ps[1])
+	}
+	if server.Version() != V2 {
+		t.Fatal("codec did not negotiate v2")
+	}
+	if _, err = server.PackHeader(h, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPackCodecCompression(t *testing.T) {
+	client, server := NewPackCodec(), NewPackCodec()
+	client.EnableCompression(64)
+	server.EnableCompression(64, CompressionDeflate)
+
+	offer, err := client.Handshake()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps, err := server.Unpack(offer)
+	if err != nil || len(ps) != 1 {
+		t.Fatalf("offer = %v %v", ps, err)
+	}
+	var answer []byte
+	if err = server.Accept(ps[0], func(b []byte) error { answer = b; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if server.Compression() != CompressionDeflate {
+		t.Fatalf("server negotiated %d", server.Compression())
+	}
+
+	before := CompressionStats()["deflate"]
+	payload := bytes.Repeat([]byte("snapshot "), 200)
+	b, err := server.Pack(InternalData, 3, 5, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) >= len(payload) {
+		t.Fatalf("packet of %d bytes not compressed", len(b))
+	}
+	small, _ := server.Pack(Data, 4, 0, []byte("tiny"))
+
+	// the answer and the first compressed packet arrive in the same read
+	if err = client.Feed(append(append(answer, b...), small...)); err != nil {
+		t.Fatal(err)
+	}
+	pk, err := client.Next()
+	if err != nil || pk.Type() != Heartbeat {
+		t.Fatalf("answer = %v %v", pk, err)
+	}
+	if err = client.Accept(pk, nil); err != nil {
+		t.Fatal(err)
+	}
+	if client.Compression() != CompressionDeflate {
+		t.Fatalf("client negotiated %d", client.Compression())
+	}
+	ps = nil
+	for {
+		pk, err := client.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pk == nil {
+			break
+		}
+		ps = append(ps, pk)
+	}
+	if len(ps) != 2 {
+		t.Fatalf("packets = %v", ps)
+	}
+	if !bytes.Equal(ps[0].Data(), payload) || ps[0].SID() != 5 || ps[0].Flags()&FlagCompressed != 0 {
+		t.Fatalf("decompressed %v", ps[0])
+	}
+	if string(ps[1].Data()) != "tiny" {
+		t.Fatalf("small packet %q", ps[1].Data())
+	}
+	after := CompressionStats()["deflate"]
+	if after.Packets != before.Packets+1 || after.Ratio <= 0 || after.Ratio >= 1 {
+		t.Fatalf("stats %+v", after)
+	}
+}
+
+func TestPackCodecEncryption(t *testing.T) {
+	client, server := NewPackCodec(), NewPackCodec()
+	client.EnableEncryption()
+	client.EnableCompression(64)
+	server.EnableCompression(64)
+
+	offer, err := client.Handshake()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps, _ := server.Unpack(offer)
+	var answer []byte
+	if err = server.Accept(ps[0], func(b []byte) error { answer = b; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	// packed before the answer was written: still plaintext
+	early, _ := server.Pack(Data, 1, 0, []byte("early"))
+	if answer, err = server.Seal(append(early, answer...)); err != nil {
+		t.Fatal(err)
+	}
+	ps, err = client.Unpack(answer)
+	if err != nil || len(ps) != 2 || string(ps[0].Data()) != "early" {
+		t.Fatalf("answer = %v %v", ps, err)
+	}
+	if err = client.Accept(ps[1], nil); err != nil || !client.Encrypted() || !server.Encrypted() {
+		t.Fatalf("key exchange %v", err)
+	}
+
+	b, _ := client.PackHeader(Header{Type: ClientData, ID: 2, SID: 9, Seq: 77}, []byte("secret"))
+	sealed, err := client.Seal(b)
+	if err != nil || bytes.Contains(sealed, []byte("secret")) {
+		t.Fatalf("sealed = %q %v", sealed, err)
+	}
+	ps, err = server.Unpack(sealed)
+	if err != nil || len(ps) != 1 || string(ps[0].Data()) != "secret" || ps[0].SID() != 9 || ps[0].Seq() != 77 {
+		t.Fatalf("opened = %v %v", ps, err)
+	}
+	if _, err = server.Unpack(sealed); !errors.Is(err, ErrEncryption) {
+		t.Fatalf("replay accepted: %v", err)
+	}
+	if _, err = server.Unpack(b); !errors.Is(err, ErrEncryption) {
+		t.Fatalf("plaintext accepted: %v", err)
+	}
+	keepalive, _ := client.Pack(Heartbeat, 0, 0, nil)
+	if _, err = server.Unpack(keepalive); err != nil {
+		t.Fatalf("keepalive rejected: %v", err)
+	}
+	forged, _ := client.PackHeader(Header{Type: Heartbeat, Ext: []Extension{{Key: ExtCompression, Value: []byte{byte(CompressionNone)}}}}, nil)
+	if _, err = server.Unpack(forged); !errors.Is(err, ErrEncryption) {
+		t.Fatalf("unauthenticated handshake accepted: %v", err)
+	}
+
+	// forwarded v1 bytes and compressed payloads are sealed too
+	legacy, _ := NewPackCodec().Pack(Data, 3, 0, []byte("forwarded"))
+	large, _ := server.Pack(Data, 4, 0, bytes.Repeat([]byte("x"), 500))
+	sealed, err = server.Seal(append(legacy, large...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps, err = client.Unpack(sealed)
+	if err != nil || len(ps) != 2 || string(ps[0].Data()) != "forwarded" || len(ps[1].Data()) != 500 {
+		t.Fatalf("server packets = %v %v", ps, err)
+	}
+	if _, err = NewPackCodec().Unpack(sealed); !errors.Is(err, ErrEncryption) {
+		t.Fatalf("encrypted packet without key: %v", err)
+	}
+	sealed, _ = server.Seal(legacy)
+	sealed[len(sealed)-1] ^= 1
+	if _, err = client.Unpack(sealed); !errors.Is(err, ErrEncryption) {
+		t.Fatalf("tampered packet: %v", err)
+	}
+}
+
+func TestPackNested(t *testing.T) {
+	codec := NewPackCodec()
+	outer := Header{Type: ClientData, SID: 8}
+	inner := Header{Type: Data, ID: 3, SID: 8}
+	for _, n := range []int{0, 100, 5000, 100 << 10} {
+		payload := bytes.Repeat([]byte{7}, n)
+		b, err := codec.PackNested(outer, inner, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in, _ := codec.PackHeader(inner, payload)
+		want, _ := codec.PackHeader(outer, in)
+		if !bytes.Equal(b.B, want) {
+			t.Fatalf("%d bytes: nested packet differs", n)
+		}
+		b.Free()
+	}
+}
+
+func TestWritePacket(t *testing.T) {
+	client, server := NewPackCodec(), NewPackCodec()
+	client.EnableEncryption()
+	offer, _ := client.Handshake()
+	ps, _ := server.Unpack(offer)
+	w := netpoll.NewLinkBuffer(1024)
+	if err := server.Accept(ps[0], func(b []byte) error { return server.WritePacket(w, b) }); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	answer, _ := w.Next(w.Len())
+	ps, _ = client.Unpack(answer)
+	if err := client.Accept(ps[0], nil); err != nil || !client.Encrypted() {
+		t.Fatalf("key exchange %v", err)
+	}
+	b1, _ := server.PackBuffer(Header{Type: Data, ID: 1}, []byte("one"))
+	b2, _ := server.PackBuffer(Header{Type: Data, ID: 2}, []byte("two"))
+	for _, b := range []*Buffer{b1, b2} {
+		if err := server.WritePacket(w, b.B); err != nil {
+			t.Fatal(err)
+		}
+		b.Free()
+	}
+	w.Flush()
+	out, _ := w.Next(w.Len())
+	ps, err := client.Unpack(out)
+	if err != nil || len(ps) != 2 || string(ps[0].Data()) != "one" || string(ps[1].Data()) != "two" {
+		t.Fatalf("packets = %v %v", ps, err)
+	}
+	if bytes.Contains(out, []byte("one")) {
+		t.Fatal("payload written in plaintext")
+	}
+}
+
+func BenchmarkPack(b *testing.B) {
+	codec, payload := NewPackCodec(), bytes.Repeat([]byte{1}, 200)
+	b.ReportAllocs()
+	for b.Loop() {
+		in, _ := codec.Pack(Data, 1, 2, payload)
+		codec.Pack(ClientData, 0, 2, in)
+	}
+}
+
+func BenchmarkPackNested(b *testing.B) {
+	codec, payload := NewPackCodec(), bytes.Repeat([]byte{1}, 200)
+	outer, inner := Header{Type: ClientData, SID: 2}, Header{Type: Data, ID: 1, SID: 2}
+	b.ReportAllocs()
+	for b.Loop() {
+		buf, _ := codec.PackNested(outer, inner, payload)
+		buf.Free()
+	}
+}
+
+func BenchmarkWritePacket(b *testing.B) {
+	codec, payload := NewPackCodec(), bytes.Repeat([]byte{1}, 200)
+	w := netpoll.NewLinkBuffer(64 << 10)
+	b.ReportAllocs()
+	for b.Loop() {
+		for range 16 {
+			buf, _ := codec.PackBuffer(Header{Type: Data, ID: 1}, payload)
+			codec.WritePacket(w, buf.B)
+			buf.Free()
+		}
+		w.Flush()
+		w.Skip(w.Len())
+		w.Release()
+	}
+}
+
+func fuzzSeeds(f *testing.F) {
+	v1, v2 := NewPackCodec(), NewPackCodecWithVersion(V2)
+	for _, codec := range []*PackCodec{v1, v2} {
+		for _, typ := range []Type{Heartbeat, Data, ClientData, InternalData, Error} {
+			b, _ := codec.Pack(typ, 7, 42, []byte("payload"))
+			f.Add(b)
+		}
+	}
+	b, _ := v2.PackHeader(Header{Type: Data, Seq: 3, Ext: []Extension{{Key: ExtTraceID, Value: []byte("t")}}}, []byte("x"))
+	f.Add(b)
+	f.Add([]byte{0, 0, 0, 4, 2})
+	f.Add([]byte{0, 0, 0, 9, 0x86, 0, 0, 0, 1})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 2, 0, 0, 0, 0})
+}
+
+// checkDecoded verifies the invariants every decoded packet must hold.
+func checkDecoded(t *testing.T, pks []*Packet, err error) {
+	t.Helper()
+	if err != nil {
+		if _, ok := errors.AsType[*ProtocolError](err); !ok {
+			t.Fatalf("untyped error %T %v", err, err)
+		}
+	}
+	for _, pk := range pks {
+		if pk.Type() < Heartbeat || pk.Type() >= Invalid || len(pk.Data()) > MaxSize(pk.Type()) {
+			t.Fatalf("invalid packet %v", pk)
+		}
+	}
+}
+
+func FuzzUnpack(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		whole, err := NewPackCodec().Unpack(data)
+		checkDecoded(t, whole, err)
+		// the same stream fed in two pieces decodes alike
+		codec := NewPackCodec()
+		cut := len(data) / 2
+		first, err1 := codec.Unpack(data[:cut])
+		checkDecoded(t, first, err1)
+		if err1 != nil {
+			return
+		}
+		second, err2 := codec.Unpack(data[cut:])
+		checkDecoded(t, second, err2)
+		if (err == nil) != (err2 == nil) || len(first)+len(second) != len(whole) {
+			t.Fatalf("split decode %d+%d packets %v, whole %d packets %v", len(first), len(second), err2, len(whole), err)
+		}
+	})
+}
+
+func FuzzNextPacket(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := netpoll.NewLinkBuffer(len(data))
+		reader.WriteBinary(data)
+		reader.Flush()
+		codec := NewPackCodec()
+		for reader.Len() >= 5 {
+			r2, err := codec.NextPacket(reader)
+			if err != nil {
+				checkDecoded(t, nil, err)
+				return
+			}
+			if r2 == nil {
+				return
+			}
+			pk, err := codec.Unpack1(r2)
+			if err != nil {
+				checkDecoded(t, nil, err)
+				continue
+			}
+			checkDecoded(t, []*Packet{pk}, nil)
+		}
+	})
+}
+
+func FuzzUnpack1(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := netpoll.NewLinkBuffer(len(data))
+		reader.WriteBinary(data)
+		reader.Flush()
+		pk, err := NewPackCodec().Unpack1(reader)
+		if err != nil && len(data) > 0 {
+			checkDecoded(t, nil, err)
+		}
+		if err == nil {
+			checkDecoded(t, []*Packet{pk}, nil)
+			if ps, err := NewPackCodec().Unpack(data); err != nil || len(ps) != 1 {
+				t.Fatalf("Unpack1 accepted what Unpack rejects: %v %v", ps, err)
+			}
+		}
+	})
+}
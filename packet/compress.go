@@ -0,0 +1,226 @@
+package packet
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Compression identifies a payload compression algorithm on the wire.
+type Compression byte
+
+const (
+	CompressionNone Compression = iota
+	CompressionDeflate
+)
+
+// DefaultCompressThreshold is the payload size from which a codec with
+// compression enabled compresses.
+const DefaultCompressThreshold = 1024
+
+var ErrCompression = errors.New("codec: compression")
+
+type Compressor interface {
+	Compress(src []byte) ([]byte, error)
+	Decompress(src []byte, limit int) ([]byte, error)
+}
+
+type compressor struct {
+	name  string
+	impl  Compressor
+	stats compressionStats
+}
+
+type compressionStats struct {
+	packets  atomic.Uint64
+	skipped  atomic.Uint64
+	inBytes  atomic.Uint64
+	outBytes atomic.Uint64
+}
+
+var (
+	compressors   = map[Compression]*compressor{}
+	compressorsrw sync.RWMutex
+)
+
+func init() {
+	RegisterCompressor(CompressionDeflate, "deflate", deflateCompressor{})
+}
+
+// RegisterCompressor makes an algorithm available to every codec. It panics
+// when c is already registered.
+func RegisterCompressor(c Compression, name string, impl Compressor) {
+	compressorsrw.Lock()
+	defer compressorsrw.Unlock()
+	if c == CompressionNone {
+		panic("packet: compression 0 is reserved")
+	}
+	if old, ok := compressors[c]; ok {
+		panic(fmt.Sprintf("packet: compression %d used by both %s and %s", c, old.name, name))
+	}
+	compressors[c] = &compressor{name: name, impl: impl}
+}
+
+func lookupCompressor(c Compression) (*compressor, bool) {
+	compressorsrw.RLock()
+	defer compressorsrw.RUnlock()
+	comp, ok := compressors[c]
+	return comp, ok
+}
+
+// Compressions returns the registered algorithms.
+func Compressions() []Compression {
+	compressorsrw.RLock()
+	defer compressorsrw.RUnlock()
+	var cs []Compression
+	for c := range compressors {
+		cs = append(cs, c)
+	}
+	slices.Sort(cs)
+	return cs
+}
+
+type CompressorStats struct {
+	Packets  uint64
+	Skipped  uint64
+	InBytes  uint64
+	OutBytes uint64
+	Ratio    float64
+}
+
+// CompressionStats returns the compression counters per algorithm name.
+// Ratio is compressed over original size of the compressed packets; Skipped
+// counts payloads sent uncompressed because compressing did not shrink them.
+func CompressionStats() map[string]CompressorStats {
+	compressorsrw.RLock()
+	defer compressorsrw.RUnlock()
+	stats := make(map[string]CompressorStats, len(compressors))
+	for _, c := range compressors {
+		s := CompressorStats{
+			Packets:  c.stats.packets.Load(),
+			Skipped:  c.stats.skipped.Load(),
+			InBytes:  c.stats.inBytes.Load(),
+			OutBytes: c.stats.outBytes.Load(),
+		}
+		if s.InBytes > 0 {
+			s.Ratio = float64(s.OutBytes) / float64(s.InBytes)
+		}
+		stats[c.name] = s
+	}
+	return stats
+}
+
+type deflateCompressor struct{}
+
+var deflateWriters = sync.Pool{New: func() any {
+	w, _ := flate.NewWriter(nil, flate.BestSpeed)
+	return w
+}}
+
+func (deflateCompressor) Compress(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := deflateWriters.Get().(*flate.Writer)
+	defer deflateWriters.Put(w)
+	w.Reset(&buf)
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (deflateCompressor) Decompress(src []byte, limit int) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(src))
+	defer r.Close()
+	b, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > limit {
+		return nil, ErrPacketSizeExcced
+	}
+	return b, nil
+}
+
+// EnableCompression lets the codec use algs, in order of preference, with
+// payloads of at least threshold bytes. A codec with compression enabled
+// answers the offers of its peer; Handshake makes the offer.
+func (p *PackCodec) EnableCompression(threshold int, algs ...Compression) {
+	if threshold <= 0 {
+		threshold = DefaultCompressThreshold
+	}
+	if len(algs) == 0 {
+		algs = Compressions()
+	}
+	p.handshakeMu.Lock()
+	p.threshold = threshold
+	p.algs = slices.Clone(algs)
+	p.handshakeMu.Unlock()
+}
+
+// Compression returns the algorithm negotiated for packets sent by p.
+func (p *PackCodec) Compression() Compression {
+	return Compression(p.compression.Load())
+}
+
+// compress returns payload compressed with the negotiated algorithm and the
+// flags to send it with, or payload itself when it is not worth it.
+func (p *PackCodec) compress(h *Header, payload []byte) ([]byte, error) {
+	if !p.compressible(h, len(payload)) {
+		return payload, nil
+	}
+	c := p.Compression()
+	comp, ok := lookupCompressor(c)
+	if !ok {
+		return nil, fmt.Errorf("%w: algorithm %d not registered", ErrCompression, c)
+	}
+	out, err := comp.impl.Compress(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %w", ErrCompression, comp.name, err)
+	}
+	if len(out) >= len(payload) {
+		comp.stats.skipped.Add(1)
+		return payload, nil
+	}
+	comp.stats.packets.Add(1)
+	comp.stats.inBytes.Add(uint64(len(payload)))
+	comp.stats.outBytes.Add(uint64(len(out)))
+	h.Flags |= FlagCompressed
+	return out, nil
+}
+
+// compressible reports whether a payload of n bytes under h is worth
+// compressing with the negotiated algorithm.
+func (p *PackCodec) compressible(h *Header, n int) bool {
+	if p.Compression() == CompressionNone || p.Version() < V2 || h.Type == Heartbeat {
+		return false
+	}
+	p.handshakeMu.Lock()
+	defer p.handshakeMu.Unlock()
+	return n >= p.threshold
+}
+
+// decompress reverses compress for a received packet.
+func (p *PackCodec) decompress(h *Header, payload []byte) ([]byte, error) {
+	if h.Flags&FlagCompressed == 0 {
+		return payload, nil
+	}
+	c := p.Compression()
+	comp, ok := lookupCompressor(c)
+	if !ok {
+		return nil, fmt.Errorf("%w: compressed packet without negotiated algorithm", ErrCompression)
+	}
+	out, err := comp.impl.Decompress(payload, MaxSize(h.Type))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %w", ErrCompression, comp.name, err)
+	}
+	h.Flags &^= FlagCompressed
+	return out, nil
+}
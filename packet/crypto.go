@@ -0,0 +1,237 @@
+package packet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/cloudwego/netpoll"
+)
+
+var ErrEncryption = errors.New("codec: encryption")
+
+const (
+	infoClientToServer = "infra-foundation client to server"
+	infoServerToClient = "infra-foundation server to client"
+
+	// counterLen is the size of the nonce counter leading an encrypted payload.
+	counterLen = 4
+)
+
+// cipherState encrypts one direction of a connection with AES-256-GCM. The
+// nonce is a packet counter sent in front of the ciphertext, apart from the
+// header sequence of the application. It starts at 1 and must grow by exactly
+// one per packet, so a replayed, dropped or reordered packet fails to open.
+type cipherState struct {
+	aead cipher.AEAD
+	mu   sync.Mutex
+	seq  uint32
+}
+
+func newCipherState(key []byte) (*cipherState, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &cipherState{aead: aead}, nil
+}
+
+func (c *cipherState) nonce(seq uint32) []byte {
+	nonce := make([]byte, c.aead.NonceSize())
+	binary.BigEndian.PutUint32(nonce[len(nonce)-4:], seq)
+	return nonce
+}
+
+// deriveCiphers derives the keys of both directions from an X25519 exchange
+// of priv with the peer key. The initiator is the side that sent the offer.
+func deriveCiphers(priv *ecdh.PrivateKey, peer []byte, initiator bool) (seal, open *cipherState, err error) {
+	pub, err := ecdh.X25519().NewPublicKey(peer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrEncryption, err)
+	}
+	shared, err := priv.ECDH(pub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrEncryption, err)
+	}
+	local := priv.PublicKey().Bytes()
+	salt := append(append([]byte{}, peer...), local...)
+	sealInfo, openInfo := infoServerToClient, infoClientToServer
+	if initiator {
+		salt = append(append([]byte{}, local...), peer...)
+		sealInfo, openInfo = openInfo, sealInfo
+	}
+	keys := make([]*cipherState, 2)
+	for i, info := range []string{sealInfo, openInfo} {
+		key, err := hkdf.Key(sha256.New, shared, salt, info, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrEncryption, err)
+		}
+		if keys[i], err = newCipherState(key); err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrEncryption, err)
+		}
+	}
+	return keys[0], keys[1], nil
+}
+
+// aad authenticates the header fields of an encrypted packet.
+func (h *Header) aad() []byte {
+	b := make([]byte, 18)
+	b[0] = byte(h.Type)
+	b[1] = byte(h.Flags &^ FlagExtensions)
+	binary.BigEndian.PutUint32(b[2:6], uint32(h.ID))
+	binary.BigEndian.PutUint64(b[6:14], uint64(h.SID))
+	binary.BigEndian.PutUint32(b[14:18], h.Seq)
+	return b
+}
+
+// EnableEncryption makes Handshake offer an X25519 key exchange. Once it
+// completes, every packet but Heartbeats is encrypted with AES-256-GCM under
+// keys derived from the exchange, and plaintext packets are rejected. Plain
+// Heartbeats are still accepted as keepalives, but not with handshake
+// extensions, which could not be authenticated.
+func (p *PackCodec) EnableEncryption() {
+	p.handshakeMu.Lock()
+	p.encrypt = true
+	p.handshakeMu.Unlock()
+}
+
+// Encrypted reports whether the key exchange with the peer completed.
+func (p *PackCodec) Encrypted() bool {
+	return p.opener.Load() != nil
+}
+
+// Seal encrypts the payloads of the packed packets in b once the key
+// exchange completed and returns b unchanged before. It numbers packets in
+// the order it sees them, so a connection calls it from its single writer,
+// after packets from every source were queued; this also covers packets
+// packed by other nodes and forwarded as is.
+func (p *PackCodec) Seal(b []byte) ([]byte, error) {
+	if p.sealer.Load() == nil && p.pending.Load() == nil {
+		return b, nil
+	}
+	var out []byte
+	err := p.seal(b, func(n int) ([]byte, error) {
+		out = slices.Grow(out, n)
+		out = out[:len(out)+n]
+		return out[len(out)-n:], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WritePacket writes the packed packets in b to w, sealing them as Seal does
+// straight into the memory of w. b can be reused once it returns; w is not
+// flushed.
+func (p *PackCodec) WritePacket(w netpoll.Writer, b []byte) error {
+	if p.sealer.Load() == nil && p.pending.Load() == nil {
+		dst, err := w.Malloc(len(b))
+		if err != nil {
+			return err
+		}
+		copy(dst, b)
+		return nil
+	}
+	return p.seal(b, w.Malloc)
+}
+
+func (p *PackCodec) seal(b []byte, malloc func(n int) ([]byte, error)) error {
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return ErrPacketSizeExcced
+		}
+		n := int(binary.BigEndian.Uint32(b[:4]))
+		if n < HeadLength || n > len(b) {
+			return ErrPacketSizeExcced
+		}
+		h, hlen, _, err := parseHeader(b[:n])
+		if err != nil {
+			return err
+		}
+		c := p.sealer.Load()
+		if h.Type == Heartbeat || c == nil {
+			if h.Type == Heartbeat {
+				if _, ok := h.Extension(ExtKeyExchange); ok {
+					if s := p.pending.Swap(nil); s != nil {
+						p.sealer.Store(s)
+					}
+				}
+			}
+			dst, err := malloc(n)
+			if err != nil {
+				return err
+			}
+			copy(dst, b[:n])
+			b = b[n:]
+			continue
+		}
+		c.mu.Lock()
+		if c.seq == ^uint32(0) {
+			c.mu.Unlock()
+			return fmt.Errorf("%w: counter exhausted", ErrEncryption)
+		}
+		c.seq++
+		h.Flags |= FlagEncrypted
+		slen := h.length(V2)
+		total := slen + counterLen + n - hlen + c.aead.Overhead()
+		dst, err := malloc(total)
+		if err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		h.put(dst, V2, total)
+		binary.BigEndian.PutUint32(dst[slen:slen+counterLen], c.seq)
+		c.aead.Seal(dst[slen+counterLen:slen+counterLen], c.nonce(c.seq), b[hlen:n], h.aad())
+		c.mu.Unlock()
+		b = b[n:]
+	}
+	return nil
+}
+
+// open reverses Seal for a received packet.
+func (p *PackCodec) open(h *Header, payload []byte) ([]byte, error) {
+	c := p.opener.Load()
+	if c == nil {
+		if h.Flags&FlagEncrypted != 0 {
+			return nil, fmt.Errorf("%w: encrypted packet before key exchange", ErrEncryption)
+		}
+		return payload, nil
+	}
+	if h.Flags&FlagEncrypted == 0 {
+		if h.Type == Heartbeat && len(h.Ext) == 0 {
+			return payload, nil
+		}
+		if h.Type == Heartbeat {
+			return nil, fmt.Errorf("%w: unauthenticated handshake after key exchange", ErrEncryption)
+		}
+		return nil, fmt.Errorf("%w: plaintext packet after key exchange", ErrEncryption)
+	}
+	if len(payload) < counterLen {
+		return nil, fmt.Errorf("%w: missing packet counter", ErrEncryption)
+	}
+	seq := binary.BigEndian.Uint32(payload[:counterLen])
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if seq != c.seq+1 {
+		return nil, fmt.Errorf("%w: counter %d after %d", ErrEncryption, seq, c.seq)
+	}
+	out, err := c.aead.Open(nil, c.nonce(seq), payload[counterLen:], h.aad())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEncryption, err)
+	}
+	c.seq = seq
+	h.Flags &^= FlagEncrypted
+	return out, nil
+}
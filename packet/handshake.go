@@ -0,0 +1,129 @@
+package packet
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"fmt"
+	"slices"
+)
+
+// Handshake returns a V2 Heartbeat offering the enabled compression
+// algorithms and, with encryption enabled, a key exchange to the peer, and
+// moves p to V2. The peer answers with a Heartbeat handled by Accept. An
+// encrypting client must not send other packets before the answer arrived.
+func (p *PackCodec) Handshake() ([]byte, error) {
+	p.handshakeMu.Lock()
+	var ext []Extension
+	if len(p.algs) > 0 {
+		offer := make([]byte, len(p.algs))
+		for i, c := range p.algs {
+			offer[i] = byte(c)
+		}
+		ext = append(ext, Extension{Key: ExtCompression, Value: offer})
+	}
+	if p.encrypt {
+		priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			p.handshakeMu.Unlock()
+			return nil, fmt.Errorf("%w: %w", ErrEncryption, err)
+		}
+		p.priv = priv
+		ext = append(ext, Extension{Key: ExtKeyExchange, Value: priv.PublicKey().Bytes()})
+	}
+	p.offered = true
+	p.handshakeMu.Unlock()
+	p.version.Store(uint32(V2))
+	return p.PackHeader(Header{Type: Heartbeat, Ext: ext}, nil)
+}
+
+// Accept handles the handshake extensions of a Heartbeat from the peer.
+//
+// For an offer it picks the first offered algorithm p has enabled, answers a
+// key exchange with its own key and sends the answer with send. Compression
+// starts once the answer is sent and encryption once Seal passes the answer,
+// so the peer gets neither before the answer. A codec answers key exchanges
+// even when it does not offer encryption itself.
+//
+// For an answer to Handshake it starts compressing with the chosen algorithm
+// and encrypting with the exchanged key.
+func (p *PackCodec) Accept(pk *Packet, send func([]byte) error) error {
+	if pk.Type() != Heartbeat {
+		return nil
+	}
+	h := pk.Header()
+	comp, hasComp := h.Extension(ExtCompression)
+	key, hasKey := h.Extension(ExtKeyExchange)
+	if !hasComp && !hasKey {
+		return nil
+	}
+	if hasKey && p.opener.Load() != nil {
+		return fmt.Errorf("%w: repeated key exchange", ErrEncryption)
+	}
+	p.handshakeMu.Lock()
+	offered, algs, priv := p.offered, p.algs, p.priv
+	p.offered, p.priv = false, nil
+	p.handshakeMu.Unlock()
+	if offered {
+		return p.accepted(comp, hasComp, key, hasKey, algs, priv)
+	}
+
+	var ext []Extension
+	chosen := CompressionNone
+	if hasComp {
+		for _, c := range comp {
+			if slices.Contains(algs, Compression(c)) {
+				chosen = Compression(c)
+				break
+			}
+		}
+		ext = append(ext, Extension{Key: ExtCompression, Value: []byte{byte(chosen)}})
+	}
+	var seal, open *cipherState
+	if hasKey {
+		priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrEncryption, err)
+		}
+		if seal, open, err = deriveCiphers(priv, key, false); err != nil {
+			return err
+		}
+		ext = append(ext, Extension{Key: ExtKeyExchange, Value: priv.PublicKey().Bytes()})
+		p.pending.Store(seal)
+		p.opener.Store(open)
+	}
+	answer, err := p.PackHeader(Header{Type: Heartbeat, Ext: ext}, nil)
+	if err != nil {
+		return err
+	}
+	if err = send(answer); err != nil {
+		return err
+	}
+	p.compression.Store(uint32(chosen))
+	return nil
+}
+
+// accepted applies the answer of the peer to the offer of Handshake.
+func (p *PackCodec) accepted(comp []byte, hasComp bool, key []byte, hasKey bool, algs []Compression, priv *ecdh.PrivateKey) error {
+	if hasComp {
+		if len(comp) != 1 || (Compression(comp[0]) != CompressionNone && !slices.Contains(algs, Compression(comp[0]))) {
+			return fmt.Errorf("%w: peer chose unsupported algorithm %v", ErrCompression, comp)
+		}
+		p.compression.Store(uint32(comp[0]))
+	}
+	if priv == nil {
+		if hasKey {
+			return fmt.Errorf("%w: key exchange answer without offer", ErrEncryption)
+		}
+		return nil
+	}
+	if !hasKey {
+		return fmt.Errorf("%w: peer did not answer the key exchange", ErrEncryption)
+	}
+	seal, open, err := deriveCiphers(priv, key, true)
+	if err != nil {
+		return err
+	}
+	p.opener.Store(open)
+	p.sealer.Store(seal)
+	return nil
+}
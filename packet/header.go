@@ -0,0 +1,194 @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Version is the packet header format. V1 is len(4)+type(1)+id(4) followed
+// by sid(8) for ClientData and InternalData. V2 sets the high bit of the type
+// byte and adds flags(1) and seq(4) after the id, then the sid, then the
+// extensions when FlagExtensions is set: a total length(2) and entries of
+// key(1)+length(1)+value.
+type Version byte
+
+const (
+	V1 Version = 1 + iota
+	V2
+)
+
+const (
+	v2Bit          = 0x80
+	v2HeadLength   = HeadLength + 5
+	maxExtValueLen = 0xff
+)
+
+type Flags byte
+
+const (
+	FlagCompressed Flags = 1 << iota
+	FlagEncrypted
+	FlagExtensions
+)
+
+// ExtKey identifies a header extension.
+type ExtKey byte
+
+const (
+	ExtTraceID ExtKey = 1 + iota
+	ExtCompression
+	ExtKeyExchange
+)
+
+type Extension struct {
+	Key   ExtKey
+	Value []byte
+}
+
+var (
+	ErrHeaderVersion = errors.New("codec: header field needs packet version 2")
+	ErrBadExtension  = errors.New("codec: malformed header extension")
+)
+
+// Header is the decoded header of a packet.
+type Header struct {
+	Type  Type
+	ID    int32
+	SID   int64
+	Flags Flags
+	Seq   uint32
+	Ext   []Extension
+}
+
+// Extension returns the value of the extension key.
+func (h *Header) Extension(key ExtKey) ([]byte, bool) {
+	for _, e := range h.Ext {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (h *Header) isV2() bool {
+	return h.Flags != 0 || h.Seq != 0 || len(h.Ext) > 0
+}
+
+func (h *Header) extLen() int {
+	if len(h.Ext) == 0 {
+		return 0
+	}
+	n := 2
+	for _, e := range h.Ext {
+		n += 2 + len(e.Value)
+	}
+	return n
+}
+
+func (h *Header) length(v Version) int {
+	n := HeadLength
+	if v == V2 {
+		n = v2HeadLength + h.extLen()
+	}
+	if hasSID(h.Type) {
+		n += 8
+	}
+	return n
+}
+
+func (h *Header) put(buf []byte, v Version, total int) {
+	binary.BigEndian.PutUint32(buf[:4], uint32(total))
+	buf[4] = byte(h.Type)
+	binary.BigEndian.PutUint32(buf[5:9], uint32(h.ID))
+	offset := HeadLength
+	flags := h.Flags &^ FlagExtensions
+	if len(h.Ext) > 0 {
+		flags |= FlagExtensions
+	}
+	if v == V2 {
+		buf[4] |= v2Bit
+		buf[offset] = byte(flags)
+		binary.BigEndian.PutUint32(buf[offset+1:offset+5], h.Seq)
+		offset += 5
+	}
+	if hasSID(h.Type) {
+		binary.BigEndian.PutUint64(buf[offset:offset+8], uint64(h.SID))
+		offset += 8
+	}
+	if v == V2 && len(h.Ext) > 0 {
+		binary.BigEndian.PutUint16(buf[offset:offset+2], uint16(h.extLen()-2))
+		offset += 2
+		for _, e := range h.Ext {
+			buf[offset] = byte(e.Key)
+			buf[offset+1] = byte(len(e.Value))
+			offset += 2
+			offset += copy(buf[offset:], e.Value)
+		}
+	}
+}
+
+func (h *Header) pack(v Version, payload []byte) []byte {
+	total := h.length(v) + len(payload)
+	buf := make([]byte, total)
+	h.put(buf, v, total)
+	copy(buf[h.length(v):], payload)
+	return buf
+}
+
+// parseHeader decodes the header at the start of the complete packet b and
+// returns it with its length and version.
+func parseHeader(b []byte) (Header, int, Version, error) {
+	var h Header
+	if len(b) < HeadLength {
+		return h, 0, 0, ErrPacketSizeExcced
+	}
+	v := V1
+	h.Type = Type(b[4] &^ v2Bit)
+	if b[4]&v2Bit != 0 {
+		v = V2
+	}
+	if h.Type < Heartbeat || h.Type >= Invalid {
+		return h, 0, 0, ErrWrongPacketType
+	}
+	h.ID = int32(binary.BigEndian.Uint32(b[5:9]))
+	offset := HeadLength
+	if v == V2 {
+		if len(b) < v2HeadLength {
+			return h, 0, 0, ErrPacketSizeExcced
+		}
+		h.Flags = Flags(b[offset])
+		h.Seq = binary.BigEndian.Uint32(b[offset+1 : offset+5])
+		offset += 5
+	}
+	if hasSID(h.Type) {
+		if len(b) < offset+8 {
+			return h, 0, 0, ErrPacketSizeExcced
+		}
+		h.SID = int64(binary.BigEndian.Uint64(b[offset : offset+8]))
+		offset += 8
+	}
+	if h.Flags&FlagExtensions != 0 {
+		if len(b) < offset+2 {
+			return h, 0, 0, ErrBadExtension
+		}
+		n := int(binary.BigEndian.Uint16(b[offset : offset+2]))
+		offset += 2
+		if len(b) < offset+n {
+			return h, 0, 0, ErrBadExtension
+		}
+		ext := b[offset : offset+n]
+		offset += n
+		for len(ext) > 0 {
+			if len(ext) < 2 || len(ext) < 2+int(ext[1]) {
+				return h, 0, 0, ErrBadExtension
+			}
+			h.Ext = append(h.Ext, Extension{Key: ExtKey(ext[0]), Value: ext[2 : 2+int(ext[1])]})
+			ext = ext[2+int(ext[1]):]
+		}
+	}
+	return h, offset, v, nil
+}
+
+func hasSID(typ Type) bool {
+	return typ == ClientData || typ == InternalData
+}
@@ -16,6 +16,9 @@ const (
 	InternalData
 	ClientData
 	NotifyData
+	Request
+	Response
+	Error
 	Invalid
 )
 
@@ -30,6 +33,9 @@ type Packet struct {
 	uid    int64
 	length int32
 	data   []byte
+	flags  Flags
+	seq    uint32
+	ext    []Extension
 }
 
 func New(typ Type, id int32, data []byte) *Packet {
@@ -39,6 +45,7 @@ func New(typ Type, id int32, data []byte) *Packet {
 	p.sid = 0
 	p.length = int32(len(data))
 	p.data = data
+	p.flags, p.seq, p.ext = 0, 0, nil
 	return p
 }
 
@@ -49,6 +56,13 @@ func NewInternal(typ Type, id int32, sid int64, data []byte) *Packet {
 	p.sid = sid
 	p.length = int32(len(data))
 	p.data = data
+	p.flags, p.seq, p.ext = 0, 0, nil
+	return p
+}
+
+func NewWithHeader(h Header, data []byte) *Packet {
+	p := NewInternal(h.Type, h.ID, h.SID, data)
+	p.flags, p.seq, p.ext = h.Flags, h.Seq, h.Ext
 	return p
 }
 
@@ -59,6 +73,7 @@ func (p *Packet) Free() {
 	p.sid = 0
 	p.uid = 0
 	p.data = nil
+	p.flags, p.seq, p.ext = 0, 0, nil
 	packetPool.Put(p)
 }
 
@@ -72,6 +87,14 @@ func (p *Packet) Type() Type { return p.typ }
 
 func (p *Packet) Data() []byte { return p.data }
 
+func (p *Packet) Flags() Flags { return p.flags }
+
+func (p *Packet) Seq() uint32 { return p.seq }
+
+func (p *Packet) Header() Header {
+	return Header{Type: p.typ, ID: p.id, SID: p.sid, Flags: p.flags, Seq: p.seq, Ext: p.ext}
+}
+
 func (p *Packet) String() string {
 	return fmt.Sprintf("Type: %d, ID: %d, Length: %d, Sid: %d, DataLen: %d", p.typ, p.id, p.length, p.sid, len(p.data))
 }
@@ -0,0 +1,96 @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+var ErrPacketTooShort = errors.New("codec: packet shorter than its header")
+
+// ProtocolError is a malformed packet received from the peer. Every decode
+// path returns its errors as a *ProtocolError, wrapping one of the codec
+// errors.
+type ProtocolError struct {
+	Type   Type
+	Length int
+	Err    error
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("%v (type %d, length %d)", e.Err, e.Type, e.Length)
+}
+
+func (e *ProtocolError) Unwrap() error { return e.Err }
+
+var maxSizes [Invalid]atomic.Int32
+
+func init() {
+	for typ := Heartbeat; typ < Invalid; typ++ {
+		maxSizes[typ].Store(MaxPacketSize)
+	}
+	maxSizes[Heartbeat].Store(1 << 10)
+	maxSizes[Connection].Store(64 << 10)
+	maxSizes[DisConnection].Store(64 << 10)
+	maxSizes[Error].Store(64 << 10)
+}
+
+// SetMaxSize limits packets of typ, header included, to n bytes; n is capped
+// at MaxPacketSize. Payloads are limited to it once decompressed as well.
+func SetMaxSize(typ Type, n int) {
+	if typ < Heartbeat || typ >= Invalid {
+		return
+	}
+	maxSizes[typ].Store(int32(min(n, MaxPacketSize)))
+}
+
+func MaxSize(typ Type) int {
+	if typ < Heartbeat || typ >= Invalid {
+		return 0
+	}
+	return int(maxSizes[typ].Load())
+}
+
+// checkFrame validates the length and type of the packet starting with the
+// at least 5 bytes of b and returns its length.
+func checkFrame(b []byte) (int, error) {
+	n := int(binary.BigEndian.Uint32(b[:4]))
+	typ := Type(b[4] &^ v2Bit)
+	if typ < Heartbeat || typ >= Invalid {
+		return 0, &ProtocolError{Type: typ, Length: n, Err: ErrWrongPacketType}
+	}
+	least := HeadLength
+	if b[4]&v2Bit != 0 {
+		least = v2HeadLength
+	}
+	if hasSID(typ) {
+		least += 8
+	}
+	if n < least {
+		return 0, &ProtocolError{Type: typ, Length: n, Err: ErrPacketTooShort}
+	}
+	if n > MaxSize(typ) {
+		return 0, &ProtocolError{Type: typ, Length: n, Err: ErrPacketSizeExcced}
+	}
+	return n, nil
+}
+
+// decode turns the complete packet b, already checked by checkFrame, into a
+// header and payload.
+func (p *PackCodec) decode(b []byte) (Header, []byte, error) {
+	h, hlen, v, err := parseHeader(b)
+	if err == nil {
+		p.negotiate(v)
+		var payload []byte
+		if payload, err = p.open(&h, b[hlen:]); err == nil {
+			if payload, err = p.decompress(&h, payload); err == nil {
+				return h, payload, nil
+			}
+		}
+	}
+	if _, ok := errors.AsType[*ProtocolError](err); !ok {
+		err = &ProtocolError{Type: h.Type, Length: len(b), Err: err}
+	}
+	return h, nil, err
+}
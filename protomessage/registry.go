@@ -0,0 +1,58 @@
+package protomessage
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	registry   = map[int32]ProtoMessage{}
+	registryrw sync.RWMutex
+)
+
+// Register records the message types generated by protoc-gen-msgmeta. It
+// panics when an ID is already taken by another message type.
+func Register(pbs ...ProtoMessage) {
+	registryrw.Lock()
+	defer registryrw.Unlock()
+	for _, pb := range pbs {
+		name := pb.ProtoReflect().Descriptor().FullName()
+		if old, ok := registry[pb.MessageID()]; ok && old.ProtoReflect().Descriptor().FullName() != name {
+			panic(fmt.Sprintf("protomessage: message ID %d used by both %s and %s", pb.MessageID(), old.ProtoReflect().Descriptor().FullName(), name))
+		}
+		registry[pb.MessageID()] = pb
+	}
+}
+
+// Lookup returns the registered prototype for id.
+func Lookup(id int32) (ProtoMessage, bool) {
+	registryrw.RLock()
+	defer registryrw.RUnlock()
+	pb, ok := registry[id]
+	return pb, ok
+}
+
+// New returns a new empty message of the type registered for id.
+func New(id int32) (ProtoMessage, bool) {
+	pb, ok := Lookup(id)
+	if !ok {
+		return nil, false
+	}
+	return proto.Clone(pb).(ProtoMessage), true
+}
+
+// Registered returns the registered messages ordered by ID.
+func Registered() []ProtoMessage {
+	registryrw.RLock()
+	defer registryrw.RUnlock()
+	ids := slices.Sorted(maps.Keys(registry))
+	pbs := make([]ProtoMessage, len(ids))
+	for i, id := range ids {
+		pbs[i] = registry[id]
+	}
+	return pbs
+}
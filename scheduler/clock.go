@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	AfterFunc(d time.Duration, f func()) ClockTimer
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// ClockTimer is a pending AfterFunc call. Stop reports whether it prevented
+// the call.
+type ClockTimer interface {
+	Stop() bool
+}
+
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTicker(d time.Duration) Ticker { return systemTicker{time.NewTicker(d)} }
+
+func (systemClock) AfterFunc(d time.Duration, f func()) ClockTimer { return time.AfterFunc(d, f) }
+
+type systemTicker struct{ t *time.Ticker }
+
+func (s systemTicker) C() <-chan time.Time { return s.t.C }
+func (s systemTicker) Stop()               { s.t.Stop() }
+
+// FakeClock only moves when Advance or Set is called. Tickers created from it
+// fire at most once per call, like a time.Ticker whose reader fell behind.
+// AfterFunc calls run in their own goroutine once the clock passes them.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers map[*fakeTicker]struct{}
+	timers  map[*fakeTimer]struct{}
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, tickers: map[*fakeTicker]struct{}{}, timers: map[*fakeTimer]struct{}{}}
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("scheduler: non-positive interval for FakeClock.NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{clock: f, c: make(chan time.Time, 1), period: d, next: f.now.Add(d)}
+	f.tickers[t] = struct{}{}
+	return t
+}
+
+func (f *FakeClock) AfterFunc(d time.Duration, fn func()) ClockTimer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{clock: f, at: f.now.Add(d), fn: fn}
+	if d <= 0 {
+		go fn()
+		return t
+	}
+	f.timers[t] = struct{}{}
+	return t
+}
+
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.set(f.now.Add(d))
+}
+
+func (f *FakeClock) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.set(now)
+}
+
+func (f *FakeClock) set(now time.Time) {
+	f.now = now
+	for t := range f.timers {
+		if !now.Before(t.at) {
+			delete(f.timers, t)
+			go t.fn()
+		}
+	}
+	for t := range f.tickers {
+		if now.Before(t.next) {
+			continue
+		}
+		for !now.Before(t.next) {
+			t.next = t.next.Add(t.period)
+		}
+		select {
+		case t.c <- now:
+		default:
+		}
+	}
+}
+
+type fakeTicker struct {
+	clock  *FakeClock
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	delete(t.clock.tickers, t)
+	t.clock.mu.Unlock()
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	at    time.Time
+	fn    func()
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	_, pending := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	return pending
+}
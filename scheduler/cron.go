@@ -0,0 +1,340 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	location                              *time.Location
+}
+
+type cronBounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	secondBounds = cronBounds{0, 59, nil}
+	minuteBounds = cronBounds{0, 59, nil}
+	hourBounds   = cronBounds{0, 23, nil}
+	domBounds    = cronBounds{1, 31, nil}
+	monthBounds  = cronBounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowBounds = cronBounds{0, 6, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+const starBit = 1 << 63
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// ParseCron parses a standard 5-field (minute hour dom month dow) or 6-field
+// (second minute hour dom month dow) expression. A leading "CRON_TZ=Zone" or
+// "TZ=Zone" selects the time zone, otherwise time.Local is used.
+func ParseCron(expr string) (*CronSchedule, error) {
+	return ParseCronIn(expr, time.Local)
+}
+
+func ParseCronIn(expr string, loc *time.Location) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, errors.New("cron: empty expression")
+	}
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		tz, rest, _ := strings.Cut(expr, " ")
+		_, name, _ := strings.Cut(tz, "=")
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("cron: load location %q: %w", name, err)
+		}
+		loc = l
+		expr = strings.TrimSpace(rest)
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	if m, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron: expected 5 or 6 fields, got %d in %q", len(fields), expr)
+	}
+	s := &CronSchedule{location: loc}
+	var err error
+	for i, dst := range []*uint64{&s.second, &s.minute, &s.hour, &s.dom, &s.month, &s.dow} {
+		bounds := []cronBounds{secondBounds, minuteBounds, hourBounds, domBounds, monthBounds, dowBounds}[i]
+		if *dst, err = parseCronField(fields[i], bounds); err != nil {
+			return nil, fmt.Errorf("cron: field %d %q: %w", i, fields[i], err)
+		}
+	}
+	// Sunday may be written as 7.
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	return s, nil
+}
+
+func parseCronField(field string, b cronBounds) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(field, ",") {
+		v, err := parseCronRange(part, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= v
+	}
+	return bits, nil
+}
+
+func parseCronRange(expr string, b cronBounds) (uint64, error) {
+	rangeExpr, stepExpr, hasStep := strings.Cut(expr, "/")
+	var start, end uint
+	var extra uint64
+	switch {
+	case rangeExpr == "*" || rangeExpr == "?":
+		start, end = b.min, b.max
+		extra = starBit
+	default:
+		lo, hi, isRange := strings.Cut(rangeExpr, "-")
+		var err error
+		if start, err = parseCronValue(lo, b); err != nil {
+			return 0, err
+		}
+		end = start
+		if isRange {
+			if end, err = parseCronValue(hi, b); err != nil {
+				return 0, err
+			}
+		} else if hasStep {
+			end = b.max
+		}
+	}
+	step := uint(1)
+	if hasStep {
+		n, err := strconv.ParseUint(stepExpr, 10, 8)
+		if err != nil || n == 0 {
+			return 0, fmt.Errorf("invalid step %q", stepExpr)
+		}
+		step = uint(n)
+		extra = 0
+	}
+	maxValue := b.max
+	if b.names != nil && b.max == 6 {
+		maxValue = 7
+	}
+	if start < b.min || end > maxValue || start > end {
+		return 0, fmt.Errorf("range %d-%d out of bounds [%d,%d]", start, end, b.min, b.max)
+	}
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+	return bits | extra, nil
+}
+
+func parseCronValue(s string, b cronBounds) (uint, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return uint(n), nil
+}
+
+func (s *CronSchedule) Location() *time.Location { return s.location }
+
+// Next returns the first activation strictly after t, or the zero time when
+// the expression can never match (e.g. "0 0 30 2 *"). Wall-clock times that
+// repeat when DST ends fire only once.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	from := wallClock(t.In(s.location))
+	for {
+		next := s.next(t)
+		if next.IsZero() || wallClock(next.In(s.location)).After(from) {
+			return next
+		}
+		t = next
+	}
+}
+
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+func (s *CronSchedule) next(t time.Time) time.Time {
+	orig := t.Location()
+	t = t.In(s.location)
+	t = t.Add(time.Second - time.Duration(t.Nanosecond())*time.Nanosecond)
+	added := false
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for 1<<uint(t.Month())&s.month == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, s.location)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.location)
+		}
+		t = t.AddDate(0, 0, 1)
+		// midnight may not exist on DST transition days.
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(time.Duration(-t.Hour()) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+	for 1<<uint(t.Hour())&s.hour == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, s.location)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+	for 1<<uint(t.Minute())&s.minute == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+	for 1<<uint(t.Second())&s.second == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+	return t.In(orig)
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := 1<<uint(t.Day())&s.dom != 0
+	dowMatch := 1<<uint(t.Weekday())&s.dow != 0
+	if s.dom&starBit != 0 || s.dow&starBit != 0 {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+type CronJob struct {
+	scheduler *Scheduler
+	schedule  *CronSchedule
+	fn        TimerFunc
+	mu        sync.Mutex
+	timerID   TimerID
+	next      time.Time
+	stopped   bool
+}
+
+func (j *CronJob) Next() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.next
+}
+
+func (j *CronJob) Schedule() *CronSchedule { return j.schedule }
+
+func (j *CronJob) Stop() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.stopped {
+		return false
+	}
+	j.stopped = true
+	j.next = time.Time{}
+	return j.scheduler.CancelTimer(j.timerID)
+}
+
+func (j *CronJob) arm(after time.Time) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.stopped {
+		return nil
+	}
+	next := j.schedule.Next(after)
+	if next.IsZero() {
+		j.stopped = true
+		j.next = next
+		return errors.New("cron: expression never fires")
+	}
+	return j.armAt(next)
+}
+
+func (j *CronJob) armAt(next time.Time) error {
+	id, err := j.scheduler.PushAt(next, j.fire)
+	if err != nil {
+		return err
+	}
+	j.next = next
+	j.timerID = id
+	return nil
+}
+
+// fire runs inside the scheduler's executor. If the wall clock was moved
+// backwards while the timer was pending the job is re-armed for the same
+// activation instead of running early.
+func (j *CronJob) fire() {
+	j.mu.Lock()
+	next, stopped := j.next, j.stopped
+	if !stopped && next.Sub(j.scheduler.clock.Now()) > j.scheduler.tick {
+		j.armAt(next)
+		j.mu.Unlock()
+		return
+	}
+	j.mu.Unlock()
+	if stopped {
+		return
+	}
+	try(j.fn)
+	j.arm(next)
+}
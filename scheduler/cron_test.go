@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestCronNext(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	base := time.Date(2026, 10, 19, 4, 30, 0, 0, shanghai)
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 5 * * *", time.Date(2026, 10, 19, 5, 0, 0, 0, shanghai)},
+		{"30 0 5 * * *", time.Date(2026, 10, 19, 5, 0, 30, 0, shanghai)},
+		{"0 5 * * tue", time.Date(2026, 10, 20, 5, 0, 0, 0, shanghai)},
+		{"0 0 1 * *", time.Date(2026, 11, 1, 0, 0, 0, 0, shanghai)},
+		{"*/20 * * * *", time.Date(2026, 10, 19, 4, 40, 0, 0, shanghai)},
+		{"0 12 1,15 feb *", time.Date(2027, 2, 1, 12, 0, 0, 0, shanghai)},
+		{"@weekly", time.Date(2026, 10, 25, 0, 0, 0, 0, shanghai)},
+		{"0 0 13 * 5", time.Date(2026, 10, 23, 0, 0, 0, 0, shanghai)},
+	}
+	for _, c := range cases {
+		s, err := ParseCronIn(c.expr, shanghai)
+		if err != nil {
+			t.Fatalf("%q: %v", c.expr, err)
+		}
+		if got := s.Next(base); !got.Equal(c.want) {
+			t.Fatalf("%q: next = %v, want %v", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestCronTimeZoneAndDST(t *testing.T) {
+	s, err := ParseCron("CRON_TZ=America/New_York 30 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ny := s.Location()
+	// 2026-03-08 02:30 does not exist in New York, the job moves to the next day.
+	got := s.Next(time.Date(2026, 3, 8, 0, 0, 0, 0, ny))
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Fatalf("spring forward: next = %v, want %v", got, want)
+	}
+	// 2026-11-01 01:30 happens twice, the job fires once.
+	s, _ = ParseCron("CRON_TZ=America/New_York 30 1 * * *")
+	first := s.Next(time.Date(2026, 11, 1, 0, 0, 0, 0, ny))
+	second := s.Next(first)
+	if second.Sub(first) < 24*time.Hour {
+		t.Fatalf("fall back: fired twice %v %v", first, second)
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "TZ=Nowhere/City * * * * *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Fatalf("%q: expected error", expr)
+		}
+	}
+	s, _ := ParseCron("0 0 30 2 *")
+	if !s.Next(time.Now()).IsZero() {
+		t.Fatal("expected zero time for impossible schedule")
+	}
+}
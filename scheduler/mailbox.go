@@ -0,0 +1,215 @@
+package scheduler
+
+import (
+	"errors"
+	"infra-foundation/logx"
+	"sync/atomic"
+	"time"
+)
+
+type OverflowPolicy int8
+
+const (
+	OverflowBlock OverflowPolicy = iota
+	OverflowDropNewest
+	OverflowReject
+)
+
+var (
+	ErrMailboxFull   = errors.New("scheduler: mailbox full")
+	ErrMailboxClosed = errors.New("scheduler: mailbox closed")
+)
+
+// MailboxConfig bounds the tasks accepted through Post. Capacity <= 0 keeps
+// the mailbox unbounded; PushTask and timers always bypass the limit. Clock
+// drives the mailbox timers and BlockTimeout and defaults to SystemClock.
+type MailboxConfig struct {
+	Name          string
+	Clock         Clock
+	Capacity      int
+	Policy        OverflowPolicy
+	BlockTimeout  time.Duration
+	HighWaterMark int
+}
+
+type MailboxStats struct {
+	Depth     int
+	MaxDepth  int64
+	Enqueued  uint64
+	Dropped   uint64
+	Rejected  uint64
+	Executed  uint64
+	AvgWait   time.Duration
+	MaxWait   time.Duration
+	AvgExec   time.Duration
+	MaxExec   time.Duration
+	Capacity  int
+	HighWater bool
+}
+
+type task struct {
+	fn TimerFunc
+	at time.Time
+}
+
+type mailboxStats struct {
+	maxDepth  atomic.Int64
+	enqueued  atomic.Uint64
+	dropped   atomic.Uint64
+	rejected  atomic.Uint64
+	executed  atomic.Uint64
+	waitTotal atomic.Int64
+	waitMax   atomic.Int64
+	execTotal atomic.Int64
+	execMax   atomic.Int64
+	highWater atomic.Bool
+}
+
+func (m *mailboxStats) observe(wait, exec time.Duration) {
+	m.executed.Add(1)
+	m.waitTotal.Add(int64(wait))
+	m.execTotal.Add(int64(exec))
+	storeMax(&m.waitMax, int64(wait))
+	storeMax(&m.execMax, int64(exec))
+}
+
+func storeMax(v *atomic.Int64, n int64) {
+	for {
+		old := v.Load()
+		if n <= old || v.CompareAndSwap(old, n) {
+			return
+		}
+	}
+}
+
+func NewMailbox(cfg MailboxConfig) *Scheduler {
+	s := NewSchedulerWithClock(defaultSlotNum, defaultTick, cfg.Clock)
+	s.SetMailbox(cfg)
+	return s
+}
+
+// NewMailboxOn creates a mailbox sharing the timer wheel of owner, for groups
+// of mailboxes such as the shards of a model that would otherwise each run a
+// ticker. cfg.Clock is ignored in favour of the clock of owner.
+func NewMailboxOn(cfg MailboxConfig, owner *Scheduler) *Scheduler {
+	s := newSchedulerOn(owner)
+	s.SetMailbox(cfg)
+	return s
+}
+
+func (s *Scheduler) SetMailbox(cfg MailboxConfig) {
+	if cfg.BlockTimeout <= 0 {
+		cfg.BlockTimeout = time.Second
+	}
+	if cfg.HighWaterMark <= 0 && cfg.Capacity > 0 {
+		cfg.HighWaterMark = cfg.Capacity * 3 / 4
+	}
+	s.taskLock.Lock()
+	s.mailbox = cfg
+	s.fullCond.Broadcast()
+	s.taskLock.Unlock()
+}
+
+// Post enqueues fn subject to the mailbox capacity and overflow policy.
+func (s *Scheduler) Post(fn TimerFunc) error {
+	if fn == nil {
+		return nil
+	}
+	if !s.started.Load() {
+		return ErrMailboxClosed
+	}
+	s.taskLock.Lock()
+	defer s.taskLock.Unlock()
+
+	if s.mailbox.Capacity > 0 && len(s.tasks) >= s.mailbox.Capacity {
+		switch s.mailbox.Policy {
+		case OverflowDropNewest:
+			s.stats.dropped.Add(1)
+			return nil
+		case OverflowReject:
+			s.stats.rejected.Add(1)
+			return ErrMailboxFull
+		default:
+			if err := s.waitNotFull(); err != nil {
+				s.stats.rejected.Add(1)
+				return err
+			}
+		}
+	}
+	s.enqueue(fn)
+	return nil
+}
+
+func (s *Scheduler) waitNotFull() error {
+	expired := false
+	timer := s.clock.AfterFunc(s.mailbox.BlockTimeout, func() {
+		s.taskLock.Lock()
+		expired = true
+		s.fullCond.Broadcast()
+		s.taskLock.Unlock()
+	})
+	defer timer.Stop()
+	for s.mailbox.Capacity > 0 && len(s.tasks) >= s.mailbox.Capacity {
+		if !s.started.Load() {
+			return ErrMailboxClosed
+		}
+		if expired {
+			return ErrMailboxFull
+		}
+		s.fullCond.Wait()
+	}
+	return nil
+}
+
+// enqueue must be called with taskLock held.
+func (s *Scheduler) enqueue(fn TimerFunc) {
+	s.tasks = append(s.tasks, task{fn: fn, at: s.clock.Now()})
+	s.stats.enqueued.Add(1)
+	depth := len(s.tasks)
+	storeMax(&s.stats.maxDepth, int64(depth))
+	if hwm := s.mailbox.HighWaterMark; hwm > 0 && depth >= hwm && s.stats.highWater.CompareAndSwap(false, true) {
+		logx.War.Printf("[Scheduler/Mailbox] %s depth %d reached high water mark %d", s.mailbox.Name, depth, hwm)
+	}
+	s.taskCond.Signal()
+}
+
+// onDequeue must be called with taskLock held.
+func (s *Scheduler) onDequeue() {
+	depth := len(s.tasks)
+	if s.mailbox.Capacity > 0 && depth < s.mailbox.Capacity {
+		s.fullCond.Signal()
+	}
+	if hwm := s.mailbox.HighWaterMark; hwm > 0 && depth <= hwm/2 && s.stats.highWater.CompareAndSwap(true, false) {
+		logx.Inf.Printf("[Scheduler/Mailbox] %s depth %d back under high water mark %d", s.mailbox.Name, depth, hwm)
+	}
+}
+
+func (s *Scheduler) Depth() int {
+	s.taskLock.Lock()
+	defer s.taskLock.Unlock()
+	return len(s.tasks)
+}
+
+func (s *Scheduler) Stats() MailboxStats {
+	s.taskLock.Lock()
+	depth, capacity := len(s.tasks), s.mailbox.Capacity
+	s.taskLock.Unlock()
+
+	st := MailboxStats{
+		Depth:     depth,
+		MaxDepth:  s.stats.maxDepth.Load(),
+		Enqueued:  s.stats.enqueued.Load(),
+		Dropped:   s.stats.dropped.Load(),
+		Rejected:  s.stats.rejected.Load(),
+		Executed:  s.stats.executed.Load(),
+		MaxWait:   time.Duration(s.stats.waitMax.Load()),
+		MaxExec:   time.Duration(s.stats.execMax.Load()),
+		Capacity:  capacity,
+		HighWater: s.stats.highWater.Load(),
+	}
+	if st.Executed > 0 {
+		st.AvgWait = time.Duration(s.stats.waitTotal.Load() / int64(st.Executed))
+		st.AvgExec = time.Duration(s.stats.execTotal.Load() / int64(st.Executed))
+	}
+	return st
+}
@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func blockExecutor(s *Scheduler) chan struct{} {
+	release, running := make(chan struct{}), make(chan struct{})
+	s.PushTask(func() {
+		close(running)
+		<-release
+	})
+	<-running
+	return release
+}
+
+func TestMailboxOverflowPolicies(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowReject, OverflowDropNewest, OverflowBlock} {
+		s := NewMailbox(MailboxConfig{Name: "test", Capacity: 2, Policy: policy, BlockTimeout: 10 * time.Millisecond})
+		release := blockExecutor(s)
+
+		var ran int
+		for range 2 {
+			if err := s.Post(func() { ran++ }); err != nil {
+				t.Fatalf("policy %d: %v", policy, err)
+			}
+		}
+		err := s.Post(func() { ran++ })
+		switch policy {
+		case OverflowDropNewest:
+			if err != nil || s.Stats().Dropped != 1 {
+				t.Fatalf("drop: err = %v stats = %+v", err, s.Stats())
+			}
+		default:
+			if !errors.Is(err, ErrMailboxFull) || s.Stats().Rejected != 1 {
+				t.Fatalf("policy %d: err = %v stats = %+v", policy, err, s.Stats())
+			}
+		}
+		if d := s.Depth(); d != 2 {
+			t.Fatalf("depth = %d", d)
+		}
+		close(release)
+		s.Sync()
+		if ran != 2 {
+			t.Fatalf("ran = %d", ran)
+		}
+		s.Stop()
+	}
+}
+
+func TestMailboxBlockUntilSpace(t *testing.T) {
+	s := NewMailbox(MailboxConfig{Capacity: 1, Policy: OverflowBlock, BlockTimeout: time.Second})
+	defer s.Stop()
+	release := blockExecutor(s)
+	s.Post(func() {})
+
+	done := make(chan error, 1)
+	go func() { done <- s.Post(func() {}) }()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	s.Sync()
+	if st := s.Stats(); st.Enqueued != 4 || st.Executed < 3 || st.Rejected != 0 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestMailboxSharedWheel(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	owner := NewMailbox(MailboxConfig{Name: "owner", Clock: clock})
+	defer owner.Stop()
+	shard := NewMailboxOn(MailboxConfig{Name: "owner/1"}, owner)
+	defer shard.Stop()
+	if shard.Clock() != Clock(clock) {
+		t.Fatal("shard does not use the clock of its owner")
+	}
+
+	release := blockExecutor(owner)
+	defer close(release)
+	fired := make(chan struct{})
+	if _, err := shard.PushAfter(50*time.Millisecond, func() { close(fired) }); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(50 * time.Millisecond)
+	shard.Sync()
+	select {
+	case <-fired:
+	default:
+		t.Fatal("timer of the shard did not run on the shard")
+	}
+}
+
+func TestMailboxBlockTimeoutUsesClock(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	s := NewMailbox(MailboxConfig{Clock: clock, Capacity: 1, Policy: OverflowBlock, BlockTimeout: time.Minute})
+	defer s.Stop()
+	release := blockExecutor(s)
+	defer close(release)
+	s.Post(func() {})
+
+	done := make(chan error, 1)
+	go func() { done <- s.Post(func() {}) }()
+	for waiting := false; !waiting; {
+		clock.mu.Lock()
+		waiting = len(clock.timers) == 1
+		clock.mu.Unlock()
+		runtime.Gosched()
+	}
+	clock.Advance(59 * time.Second)
+	select {
+	case err := <-done:
+		t.Fatalf("gave up before the timeout: %v", err)
+	default:
+	}
+	clock.Advance(time.Second)
+	if err := <-done; !errors.Is(err, ErrMailboxFull) {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -13,17 +13,21 @@ type Scheduler struct {
 	chDie     chan struct{}
 	taskLock  sync.Mutex
 	taskCond  *sync.Cond
-	tasks     []TimerFunc
+	fullCond  *sync.Cond
+	tasks     []task
+	mailbox   MailboxConfig
+	stats     mailboxStats
 	started   atomic.Bool
 	timeWheel *TimerWheel
 	tick      time.Duration
 	slotNum   int
+	clock     Clock
 	wg        sync.WaitGroup
 }
 
 const (
-	defaultTick    = time.Second
-	defaultSlotNum = 1024
+	defaultTick    = 10 * time.Millisecond
+	defaultSlotNum = 256
 )
 
 func NewScheduler() *Scheduler {
@@ -31,6 +35,13 @@ func NewScheduler() *Scheduler {
 }
 
 func NewSchedulerWith(slotNum int, tick time.Duration) *Scheduler {
+	return NewSchedulerWithClock(slotNum, tick, SystemClock)
+}
+
+func NewSchedulerWithClock(slotNum int, tick time.Duration, clock Clock) *Scheduler {
+	if clock == nil {
+		clock = SystemClock
+	}
 	if slotNum <= 0 {
 		slotNum = defaultSlotNum
 	}
@@ -40,12 +51,14 @@ func NewSchedulerWith(slotNum int, tick time.Duration) *Scheduler {
 
 	s := &Scheduler{
 		chDie:   make(chan struct{}),
-		tasks:   make([]TimerFunc, 0, 4096),
+		tasks:   make([]task, 0, 4096),
 		tick:    tick,
 		slotNum: slotNum,
+		clock:   clock,
 	}
 	s.taskCond = sync.NewCond(&s.taskLock)
-	s.timeWheel = newTimerWheel(slotNum, tick, s)
+	s.fullCond = sync.NewCond(&s.taskLock)
+	s.timeWheel = newTimerWheel(slotNum, tick, clock, s)
 	s.started.Store(true)
 
 	s.wg.Go(s.sched)
@@ -53,6 +66,26 @@ func NewSchedulerWith(slotNum int, tick time.Duration) *Scheduler {
 	return s
 }
 
+// newSchedulerOn creates a scheduler whose timers run on the wheel of owner
+// instead of a wheel of its own, so it starts no ticker. Its timers stop
+// firing once owner is stopped.
+func newSchedulerOn(owner *Scheduler) *Scheduler {
+	s := &Scheduler{
+		chDie:     make(chan struct{}),
+		tasks:     make([]task, 0, 4096),
+		tick:      owner.tick,
+		slotNum:   owner.slotNum,
+		clock:     owner.clock,
+		timeWheel: owner.timeWheel,
+	}
+	s.taskCond = sync.NewCond(&s.taskLock)
+	s.fullCond = sync.NewCond(&s.taskLock)
+	s.started.Store(true)
+
+	s.wg.Go(s.runExecutor)
+	return s
+}
+
 func (s *Scheduler) Stop() {
 	if !s.started.CompareAndSwap(true, false) {
 		return
@@ -61,6 +94,7 @@ func (s *Scheduler) Stop() {
 
 	s.taskLock.Lock()
 	s.taskCond.Broadcast()
+	s.fullCond.Broadcast()
 	s.taskLock.Unlock()
 
 	s.Wait()
@@ -70,14 +104,32 @@ func (s *Scheduler) Wait() {
 	s.wg.Wait()
 }
 
+func (s *Scheduler) Clock() Clock { return s.clock }
+
+// Sync processes every timer that is due at the clock's current time and
+// blocks until they and all previously pushed tasks have run. It is meant for
+// tests driven by a FakeClock.
+func (s *Scheduler) Sync() {
+	if !s.started.Load() {
+		return
+	}
+	s.timeWheel.advance()
+	done := make(chan struct{})
+	s.PushTask(func() { close(done) })
+	select {
+	case <-done:
+	case <-s.chDie:
+	}
+}
+
 func (s *Scheduler) sched() {
-	ticker := time.NewTicker(s.tick)
+	ticker := s.clock.NewTicker(s.tick)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			s.timeWheel.tickerHandler()
+		case <-ticker.C():
+			s.timeWheel.advance()
 		case <-s.chDie:
 			return
 		}
@@ -96,17 +148,21 @@ func (s *Scheduler) runExecutor() {
 			s.taskCond.Wait()
 		}
 
-		fn := s.tasks[0]
+		t := s.tasks[0]
+		s.tasks[0] = task{}
 
 		s.tasks = s.tasks[1:]
 
 		if len(s.tasks) == 0 {
 			s.tasks = s.tasks[:0]
 		}
+		s.onDequeue()
 
 		s.taskLock.Unlock()
 
-		try(fn)
+		start := s.clock.Now()
+		try(t.fn)
+		s.stats.observe(start.Sub(t.at), s.clock.Now().Sub(start))
 
 		s.taskLock.Lock()
 	}
@@ -118,8 +174,7 @@ func (s *Scheduler) PushTask(fn TimerFunc) {
 	}
 
 	s.taskLock.Lock()
-	s.tasks = append(s.tasks, fn)
-	s.taskCond.Signal()
+	s.enqueue(fn)
 	s.taskLock.Unlock()
 }
 
@@ -127,14 +182,14 @@ func (s *Scheduler) PushAfter(delay time.Duration, fn TimerFunc) (TimerID, error
 	if !s.started.Load() || fn == nil {
 		return 0, errors.New("scheduler not started or nil func")
 	}
-	return s.timeWheel.addTimer(delay, false, fn)
+	return s.timeWheel.addTimerOn(s, delay, false, fn)
 }
 
 func (s *Scheduler) PushEvery(interval time.Duration, fn TimerFunc) (TimerID, error) {
 	if !s.started.Load() || fn == nil {
 		return 0, errors.New("scheduler not started or nil func")
 	}
-	return s.timeWheel.addTimer(interval, true, fn)
+	return s.timeWheel.addTimerOn(s, interval, true, fn)
 }
 
 func (s *Scheduler) PushInfiniteTimer(interval time.Duration, infinite bool, fn TimerFunc) TimerID {
@@ -147,6 +202,29 @@ func (s *Scheduler) PushInfiniteTimer(interval time.Duration, infinite bool, fn
 	return id
 }
 
+func (s *Scheduler) PushAt(at time.Time, fn TimerFunc) (TimerID, error) {
+	return s.PushAfter(at.Sub(s.clock.Now()), fn)
+}
+
+func (s *Scheduler) PushCron(expr string, fn TimerFunc) (*CronJob, error) {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+	return s.PushSchedule(schedule, fn)
+}
+
+func (s *Scheduler) PushSchedule(schedule *CronSchedule, fn TimerFunc) (*CronJob, error) {
+	if !s.started.Load() || fn == nil {
+		return nil, errors.New("scheduler not started or nil func")
+	}
+	job := &CronJob{scheduler: s, schedule: schedule, fn: fn}
+	if err := job.arm(s.clock.Now()); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
 func (s *Scheduler) CancelTimer(id TimerID) bool {
 	return s.timeWheel.cancelTimer(id)
 }
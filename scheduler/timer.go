@@ -13,10 +13,11 @@ type TimerID uint64
 type Timer struct {
 	id        TimerID
 	fn        TimerFunc
+	target    *Scheduler
 	interval  time.Duration
 	recurring bool
-	rounds    int
-	ticks     int
+	expire    uint64
+	level     int
 	slot      int
 
 	prev *Timer
@@ -63,11 +64,26 @@ func (l *TimerList) Remove(t *Timer) {
 	t.list = nil
 }
 
+func (l *TimerList) take() *Timer {
+	head := l.head
+	l.head = nil
+	l.tail = nil
+	return head
+}
+
+const defaultLevelNum = 4
+
+// TimerWheel is a hierarchical timing wheel. Level 0 advances one slot per
+// tick; every higher level covers slotNum slots of the level below it and is
+// cascaded down when the lower level wraps.
 type TimerWheel struct {
-	slots        []*TimerList
-	current      int
+	levels       [][]*TimerList
+	spans        []uint64
 	slotNum      int
 	tick         time.Duration
+	now          uint64
+	start        time.Time
+	clock        Clock
 	lock         sync.Mutex
 	scheduler    *Scheduler
 	idSeq        atomic.Uint64
@@ -75,36 +91,94 @@ type TimerWheel struct {
 	pendingTasks []TimerFunc
 }
 
-func newTimerWheel(slotNum int, tick time.Duration, scheduler *Scheduler) *TimerWheel {
+func newTimerWheel(slotNum int, tick time.Duration, clock Clock, scheduler *Scheduler) *TimerWheel {
+	return newTimerWheelLevels(slotNum, defaultLevelNum, tick, clock, scheduler)
+}
+
+func newTimerWheelLevels(slotNum, levelNum int, tick time.Duration, clock Clock, scheduler *Scheduler) *TimerWheel {
+	if slotNum < 2 {
+		slotNum = 2
+	}
+	if levelNum <= 0 {
+		levelNum = 1
+	}
+	if clock == nil {
+		clock = SystemClock
+	}
 	tw := &TimerWheel{
-		slots:        make([]*TimerList, slotNum),
+		levels:       make([][]*TimerList, levelNum),
+		spans:        make([]uint64, levelNum+1),
 		slotNum:      slotNum,
 		tick:         tick,
+		start:        clock.Now(),
+		clock:        clock,
 		scheduler:    scheduler,
 		index:        make(map[TimerID]*Timer),
 		pendingTasks: make([]TimerFunc, 0, 128),
 	}
-	for i := range slotNum {
-		tw.slots[i] = &TimerList{}
+	tw.spans[0] = 1
+	for i := range levelNum {
+		tw.levels[i] = make([]*TimerList, slotNum)
+		for j := range slotNum {
+			tw.levels[i][j] = &TimerList{}
+		}
+		tw.spans[i+1] = tw.spans[i] * uint64(slotNum)
 	}
 	return tw
 }
 
-func (t *TimerWheel) plan(interval time.Duration) (ticks int, rounds int, slot int) {
+func (t *TimerWheel) ticksOf(interval time.Duration) uint64 {
 	if interval <= 0 {
-		interval = t.tick
+		return 1
 	}
-	ticks = int(interval / t.tick)
-	if ticks <= 0 {
+	ticks := uint64((interval + t.tick - 1) / t.tick)
+	if ticks == 0 {
 		ticks = 1
 	}
-	rounds = ticks / t.slotNum
-	slotOffset := ticks % t.slotNum
-	slot = (t.current + slotOffset) % t.slotNum
-	return
+	return ticks
+}
+
+// expireAt converts a delay into an absolute tick, measured from the real
+// elapsed time so that timers added between two ticks never fire early.
+func (t *TimerWheel) expireAt(interval time.Duration) uint64 {
+	if interval < 0 {
+		interval = 0
+	}
+	expire := uint64((t.clock.Now().Sub(t.start) + interval + t.tick - 1) / t.tick)
+	if expire <= t.now {
+		expire = t.now + 1
+	}
+	return expire
+}
+
+func (t *TimerWheel) place(timer *Timer) {
+	delta := uint64(1)
+	if timer.expire > t.now {
+		delta = timer.expire - t.now
+	}
+	top := len(t.levels) - 1
+	level := 0
+	for level < top && delta >= t.spans[level+1] {
+		level++
+	}
+	var slot int
+	if delta >= t.spans[top+1] {
+		slot = int((t.now/t.spans[top] + uint64(t.slotNum) - 1) % uint64(t.slotNum))
+	} else {
+		slot = int((timer.expire / t.spans[level]) % uint64(t.slotNum))
+	}
+	timer.level = level
+	timer.slot = slot
+	t.levels[level][slot].PushBack(timer)
 }
 
 func (t *TimerWheel) addTimer(interval time.Duration, recurring bool, fn TimerFunc) (TimerID, error) {
+	return t.addTimerOn(t.scheduler, interval, recurring, fn)
+}
+
+// addTimerOn adds a timer whose function runs on target, which may be a
+// scheduler sharing the wheel rather than its owner.
+func (t *TimerWheel) addTimerOn(target *Scheduler, interval time.Duration, recurring bool, fn TimerFunc) (TimerID, error) {
 	if fn == nil {
 		return 0, nil
 	}
@@ -112,18 +186,15 @@ func (t *TimerWheel) addTimer(interval time.Duration, recurring bool, fn TimerFu
 	defer t.lock.Unlock()
 
 	id := TimerID(t.idSeq.Add(1))
-	ticks, rounds, slot := t.plan(interval)
-
 	timer := &Timer{
 		id:        id,
 		fn:        fn,
+		target:    target,
 		interval:  interval,
 		recurring: recurring,
-		rounds:    rounds,
-		ticks:     ticks,
-		slot:      slot,
+		expire:    t.expireAt(interval),
 	}
-	t.slots[slot].PushBack(timer)
+	t.place(timer)
 	t.index[id] = timer
 	return id, nil
 }
@@ -142,40 +213,76 @@ func (t *TimerWheel) cancelTimer(id TimerID) bool {
 	return true
 }
 
+func (t *TimerWheel) cascade() {
+	for level := len(t.levels) - 1; level > 0; level-- {
+		if t.now%t.spans[level] != 0 {
+			continue
+		}
+		slot := int((t.now / t.spans[level]) % uint64(t.slotNum))
+		for timer := t.levels[level][slot].take(); timer != nil; {
+			next := timer.next
+			timer.prev, timer.next, timer.list = nil, nil, nil
+			t.place(timer)
+			timer = next
+		}
+	}
+}
+
+// advance processes every tick that should have elapsed by now, so a late
+// ticker does not shift timers.
+func (t *TimerWheel) advance() {
+	elapsed := t.clock.Now().Sub(t.start)
+	if elapsed < 0 {
+		return
+	}
+	target := uint64(elapsed / t.tick)
+	for {
+		t.lock.Lock()
+		if t.now >= target {
+			t.lock.Unlock()
+			return
+		}
+		t.tickAndUnlock()
+	}
+}
+
 func (t *TimerWheel) tickerHandler() {
 	t.lock.Lock()
-	slot := t.slots[t.current]
-	t.pendingTasks = t.pendingTasks[:0]
+	t.tickAndUnlock()
+}
 
-	for timer := slot.head; timer != nil; {
+// tickAndUnlock must be called with t.lock held. Expired timers are handed to
+// their scheduler before the lock is released so that each executor sees them
+// in tick order.
+func (t *TimerWheel) tickAndUnlock() {
+	t.now++
+	t.cascade()
+
+	slot := t.levels[0][int(t.now%uint64(t.slotNum))]
+	t.pendingTasks = t.pendingTasks[:0]
+	for timer := slot.take(); timer != nil; {
 		next := timer.next
-		if timer.rounds > 0 {
-			timer.rounds--
-			timer = next
-			continue
+		timer.prev, timer.next, timer.list = nil, nil, nil
+		if timer.target != nil {
+			timer.target.PushTask(timer.fn)
+		} else {
+			t.pendingTasks = append(t.pendingTasks, timer.fn)
 		}
 
-		t.pendingTasks = append(t.pendingTasks, timer.fn)
-
-		slot.Remove(timer)
-		delete(t.index, timer.id)
-
 		if timer.recurring {
-			ticks, rounds, slotIdx := t.plan(timer.interval)
-			timer.ticks = ticks
-			timer.rounds = rounds
-			timer.slot = slotIdx
-			t.slots[slotIdx].PushBack(timer)
-			t.index[timer.id] = timer
+			timer.expire += t.ticksOf(timer.interval)
+			if timer.expire <= t.now {
+				timer.expire = t.now + 1
+			}
+			t.place(timer)
+		} else {
+			delete(t.index, timer.id)
 		}
 		timer = next
 	}
-	t.current = (t.current + 1) % t.slotNum
+	pending := t.pendingTasks
 	t.lock.Unlock()
-
-	if len(t.pendingTasks) > 0 && t.scheduler != nil {
-		for _, fn := range t.pendingTasks {
-			t.scheduler.PushTask(fn)
-		}
+	for _, fn := range pending {
+		try(fn)
 	}
 }
@@ -1,18 +1,215 @@
 package scheduler
 
 import (
-	"log"
 	"testing"
 	"time"
 )
 
 func TestPushTimer(t *testing.T) {
-	s := NewScheduler()
-	s.PushInfiniteTimer(time.Second, false, func() {
-		log.Println("hello world 1111111111111")
-	})
-	s.PushInfiniteTimer(time.Second*5, true, func() {
-		log.Println("hello world 2222222222222")
-	})
-	time.Sleep(time.Second * 21)
+	clock := NewFakeClock(time.Unix(0, 0))
+	s := NewSchedulerWithClock(0, 0, clock)
+	defer s.Stop()
+
+	var once, every int
+	s.PushInfiniteTimer(time.Second, false, func() { once++ })
+	s.PushInfiniteTimer(time.Second*5, true, func() { every++ })
+	for range 21 {
+		clock.Advance(time.Second)
+		s.Sync()
+	}
+	if once != 1 || every != 4 {
+		t.Fatalf("once = %d every = %d", once, every)
+	}
+}
+
+func TestPushAfterMilliseconds(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	s := NewSchedulerWithClock(0, 0, clock)
+	defer s.Stop()
+
+	var fired bool
+	s.PushAfter(50*time.Millisecond, func() { fired = true })
+	clock.Advance(40 * time.Millisecond)
+	s.Sync()
+	if fired {
+		t.Fatal("fired early")
+	}
+	clock.Advance(10 * time.Millisecond)
+	s.Sync()
+	if !fired {
+		t.Fatal("not fired")
+	}
+}
+
+func TestPushCron(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 10, 19, 4, 59, 0, 0, time.UTC))
+	s := NewSchedulerWithClock(0, 0, clock)
+	defer s.Stop()
+
+	var runs int
+	job, err := s.PushCron("CRON_TZ=UTC 0 5 * * *", func() { runs++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 10, 19, 5, 0, 0, 0, time.UTC); !job.Next().Equal(want) {
+		t.Fatalf("next = %v, want %v", job.Next(), want)
+	}
+	clock.Advance(time.Minute)
+	s.Sync()
+	s.Sync()
+	if runs != 1 {
+		t.Fatalf("runs = %d", runs)
+	}
+	if want := time.Date(2026, 10, 20, 5, 0, 0, 0, time.UTC); !job.Next().Equal(want) {
+		t.Fatalf("next = %v, want %v", job.Next(), want)
+	}
+	job.Stop()
+}
+
+func TestTimerWheelLevels(t *testing.T) {
+	tw := newTimerWheelLevels(8, 3, time.Hour, NewFakeClock(time.Unix(0, 0)), nil)
+	fired := map[int]uint64{}
+	delays := []int{1, 2, 7, 8, 9, 63, 64, 65, 100, 511, 512, 513, 2000}
+	for _, d := range delays {
+		tw.addTimer(time.Duration(d)*time.Hour, false, func() { fired[d] = tw.now })
+	}
+	for range 2100 {
+		tw.tickerHandler()
+	}
+	for _, d := range delays {
+		if fired[d] != uint64(d) {
+			t.Fatalf("timer %d fired at %d", d, fired[d])
+		}
+	}
+	if len(tw.index) != 0 {
+		t.Fatalf("index len = %d", len(tw.index))
+	}
+}
+
+func TestTimerWheelRecurringAndCancel(t *testing.T) {
+	tw := newTimerWheelLevels(4, 2, time.Hour, NewFakeClock(time.Unix(0, 0)), nil)
+	var hits []uint64
+	id, _ := tw.addTimer(3*time.Hour, true, func() { hits = append(hits, tw.now) })
+	cancelID, _ := tw.addTimer(5*time.Hour, false, func() { t.Fatal("canceled timer fired") })
+	if !tw.cancelTimer(cancelID) {
+		t.Fatal("cancel failed")
+	}
+	for range 10 {
+		tw.tickerHandler()
+	}
+	tw.cancelTimer(id)
+	for range 10 {
+		tw.tickerHandler()
+	}
+	if len(hits) != 3 || hits[0] != 3 || hits[1] != 6 || hits[2] != 9 {
+		t.Fatalf("hits = %v", hits)
+	}
+}
+
+func BenchmarkTimerWheelAddCancel(b *testing.B) {
+	tw := newTimerWheel(defaultSlotNum, defaultTick, nil, nil)
+	fn := func() {}
+	for i := 0; b.Loop(); i++ {
+		id, _ := tw.addTimer(time.Duration(i%86400)*time.Second, false, fn)
+		tw.cancelTimer(id)
+	}
+}
+
+func BenchmarkLegacyTimerWheelAddCancel(b *testing.B) {
+	tw := newLegacyTimerWheel(1024, time.Second)
+	fn := func() {}
+	for i := 0; b.Loop(); i++ {
+		id := tw.addTimer(time.Duration(i%86400)*time.Second, fn)
+		tw.cancelTimer(id)
+	}
+}
+
+func BenchmarkTimerWheelTick(b *testing.B) {
+	tw := newTimerWheel(defaultSlotNum, defaultTick, nil, nil)
+	fn := func() {}
+	for i := range 100000 {
+		tw.addTimer(time.Duration(i)*time.Second, false, fn)
+	}
+	for b.Loop() {
+		tw.tickerHandler()
+	}
+}
+
+func BenchmarkLegacyTimerWheelTick(b *testing.B) {
+	tw := newLegacyTimerWheel(1024, time.Second)
+	fn := func() {}
+	for i := range 100000 {
+		tw.addTimer(time.Duration(i)*time.Second, fn)
+	}
+	for b.Loop() {
+		tw.tickerHandler()
+	}
+}
+
+// legacyTimerWheel is the previous single-level wheel, kept for benchmarks.
+type legacyTimer struct {
+	id     TimerID
+	fn     TimerFunc
+	rounds int
+	prev   *legacyTimer
+	next   *legacyTimer
+	slot   int
+}
+
+type legacyTimerWheel struct {
+	slots   []*legacyTimer
+	current int
+	slotNum int
+	tick    time.Duration
+	idSeq   TimerID
+	index   map[TimerID]*legacyTimer
+}
+
+func newLegacyTimerWheel(slotNum int, tick time.Duration) *legacyTimerWheel {
+	return &legacyTimerWheel{slots: make([]*legacyTimer, slotNum), slotNum: slotNum, tick: tick, index: map[TimerID]*legacyTimer{}}
+}
+
+func (t *legacyTimerWheel) addTimer(interval time.Duration, fn TimerFunc) TimerID {
+	ticks := max(int(interval/t.tick), 1)
+	t.idSeq++
+	timer := &legacyTimer{id: t.idSeq, fn: fn, rounds: ticks / t.slotNum, slot: (t.current + ticks%t.slotNum) % t.slotNum}
+	timer.next = t.slots[timer.slot]
+	if timer.next != nil {
+		timer.next.prev = timer
+	}
+	t.slots[timer.slot] = timer
+	t.index[timer.id] = timer
+	return timer.id
+}
+
+func (t *legacyTimerWheel) remove(timer *legacyTimer) {
+	if timer.prev != nil {
+		timer.prev.next = timer.next
+	} else {
+		t.slots[timer.slot] = timer.next
+	}
+	if timer.next != nil {
+		timer.next.prev = timer.prev
+	}
+	delete(t.index, timer.id)
+}
+
+func (t *legacyTimerWheel) cancelTimer(id TimerID) {
+	if timer, ok := t.index[id]; ok {
+		t.remove(timer)
+	}
+}
+
+func (t *legacyTimerWheel) tickerHandler() {
+	for timer := t.slots[t.current]; timer != nil; {
+		next := timer.next
+		if timer.rounds > 0 {
+			timer.rounds--
+		} else {
+			t.remove(timer)
+			timer.fn()
+		}
+		timer = next
+	}
+	t.current = (t.current + 1) % t.slotNum
 }
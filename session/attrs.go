@@ -0,0 +1,150 @@
+package session
+
+import (
+	"encoding/json"
+	"maps"
+	"sync"
+)
+
+// Attrs is the attribute bag of a session. Values are local to the node
+// unless set through a replicated Attr, in which case their encoded form is
+// also kept so it can be sent to the other nodes the session is bound to.
+type Attrs struct {
+	mu         sync.RWMutex
+	values     map[string]any
+	replicated map[string][]byte
+}
+
+func (a *Attrs) get(name string) (any, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	v, ok := a.values[name]
+	return v, ok
+}
+
+func (a *Attrs) set(name string, v any, data []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.values == nil {
+		a.values = map[string]any{}
+	}
+	a.values[name] = v
+	if data != nil {
+		if a.replicated == nil {
+			a.replicated = map[string][]byte{}
+		}
+		a.replicated[name] = data
+	}
+}
+
+func (a *Attrs) delete(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.values, name)
+	delete(a.replicated, name)
+}
+
+// Replicated returns the encoded replicated attributes.
+func (a *Attrs) Replicated() map[string][]byte {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return maps.Clone(a.replicated)
+}
+
+// Apply stores replicated attributes received from another node. An empty
+// value deletes the attribute. Values are decoded lazily by the Attr reading
+// them.
+func (a *Attrs) Apply(attrs map[string][]byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for name, data := range attrs {
+		if len(data) == 0 {
+			delete(a.values, name)
+			delete(a.replicated, name)
+			continue
+		}
+		if a.values == nil {
+			a.values = map[string]any{}
+		}
+		if a.replicated == nil {
+			a.replicated = map[string][]byte{}
+		}
+		a.values[name] = remoteValue(data)
+		a.replicated[name] = data
+	}
+}
+
+type remoteValue []byte
+
+// AttrReplicator is implemented by sessions that forward replicated
+// attributes to the other nodes the session is bound to.
+type AttrReplicator interface {
+	ReplicateAttrs(attrs map[string][]byte) error
+}
+
+// Attr is a typed session attribute. Replicated attributes are JSON encoded
+// and readable on every node the session is bound to.
+type Attr[T any] struct {
+	name       string
+	replicated bool
+}
+
+// NewAttr declares an attribute kept on the local node only.
+func NewAttr[T any](name string) Attr[T] {
+	return Attr[T]{name: name}
+}
+
+// NewReplicatedAttr declares an attribute replicated to the nodes the
+// session is bound to.
+func NewReplicatedAttr[T any](name string) Attr[T] {
+	return Attr[T]{name: name, replicated: true}
+}
+
+func (k Attr[T]) Name() string { return k.name }
+
+func (k Attr[T]) Get(s Session) (T, bool) {
+	var zero T
+	v, ok := s.Attrs().get(k.name)
+	if !ok {
+		return zero, false
+	}
+	if data, remote := v.(remoteValue); remote {
+		var t T
+		if err := json.Unmarshal(data, &t); err != nil {
+			return zero, false
+		}
+		return t, true
+	}
+	t, ok := v.(T)
+	return t, ok
+}
+
+// Set stores v on s and, for a replicated attribute, sends it to the other
+// nodes of the session.
+func (k Attr[T]) Set(s Session, v T) error {
+	if !k.replicated {
+		s.Attrs().set(k.name, v, nil)
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.Attrs().set(k.name, v, data)
+	return replicate(s, map[string][]byte{k.name: data})
+}
+
+func (k Attr[T]) Delete(s Session) error {
+	s.Attrs().delete(k.name)
+	if !k.replicated {
+		return nil
+	}
+	return replicate(s, map[string][]byte{k.name: nil})
+}
+
+func replicate(s Session, attrs map[string][]byte) error {
+	if r, ok := s.(AttrReplicator); ok {
+		return r.ReplicateAttrs(attrs)
+	}
+	return nil
+}
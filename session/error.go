@@ -0,0 +1,35 @@
+package session
+
+import (
+	"fmt"
+	protomessage "infra-foundation/protomessage"
+)
+
+// CodeInternal is reported to the client for handler errors that carry no
+// code of their own.
+const CodeInternal int32 = -1
+
+// CodeError is a handler error that is sent back to the client as an error
+// packet.
+type CodeError struct {
+	Code    int32
+	Message string
+}
+
+func NewError(code int32, msg string) *CodeError {
+	return &CodeError{Code: code, Message: msg}
+}
+
+func (e *CodeError) Error() string {
+	return fmt.Sprintf("code %d: %s", e.Code, e.Message)
+}
+
+// Replier is implemented by sessions that can answer a client request. seq
+// is the header sequence of the request, echoed so the client can match the
+// answer to its call; it is 0 for requests that carried none.
+type Replier interface {
+	Reply(seq uint32, pb protomessage.ProtoMessage) error
+	ReplyError(seq uint32, id int32, code int32, msg string) error
+}
+
+type ReplyHandlerFunc func(Session, protomessage.ProtoMessage) (protomessage.ProtoMessage, error)
@@ -54,6 +54,7 @@ type Session interface {
 	GetServers(name string) string
 	BindServers(name, id string)
 	Servers() map[string]string
+	Attrs() *Attrs
 	Send(pb protomessage.ProtoMessage) error
 	Notify(s []Session, pb protomessage.ProtoMessage) error
 	Close() error
@@ -64,6 +65,7 @@ type NetworkEntities struct {
 	Uid       atomic.Int64
 	servers   map[string]string
 	serversrw sync.RWMutex
+	attrs     Attrs
 }
 
 func NewNetworkEntities(id, uid int64) *NetworkEntities {
@@ -78,6 +80,8 @@ func (n *NetworkEntities) UID() int64        { return n.Uid.Load() }
 func (n *NetworkEntities) BindID(id int64)   { n.Id.Store(id) }
 func (n *NetworkEntities) BindUID(uid int64) { n.Uid.Store(uid) }
 
+func (n *NetworkEntities) Attrs() *Attrs { return &n.attrs }
+
 func (n *NetworkEntities) GetServers(name string) string {
 	n.serversrw.RLock()
 	defer n.serversrw.RUnlock()